@@ -1,3 +1,3 @@
 module github.com/suzuki-shunsuke/go-thread-safe
 
-go 1.19
+go 1.23
@@ -1,70 +1,270 @@
 package safe
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"strconv"
-	"sync"
+	"sync/atomic"
 )
 
 // Bool wraps bool.
-// Bool must be used as the pointer because Bool has sync.RWMutex as a private field.
-// A RWMutex must not be copied after first use.
-// https://golang.org/pkg/sync/#RWMutex
+// Bool is lock-free, all operations are done with sync/atomic.
+// So Bool doesn't implement Locker and can't be locked by LockAll and Atomically.
+// To change a flag together with other containers, use Value[bool] instead.
+// Bool must be used as the pointer because Bool must not be copied after first use.
+// https://golang.org/pkg/sync/atomic/#Bool
 type Bool struct {
-	value bool
-	mutex sync.RWMutex
+	value    atomic.Bool
+	watchers watchers[bool]
+	waiters  waiters[bool]
 }
 
 func (b *Bool) String() string {
-	b.mutex.RLock()
-	v := b.value
-	b.mutex.RUnlock()
-	return "Bool{" + strconv.FormatBool(v) + "}"
+	return "Bool{" + strconv.FormatBool(b.Get()) + "}"
 }
 
 func (b *Bool) MarshalJSON() ([]byte, error) {
-	b.mutex.RLock()
-	v := b.value
-	b.mutex.RUnlock()
-	return json.Marshal(v)
+	return json.Marshal(b.Get())
 }
 
 func (b *Bool) UnmarshalJSON(buf []byte) error {
-	b.mutex.Lock()
-	err := json.Unmarshal(buf, &b.value)
-	b.mutex.Unlock()
-	return err
+	var v bool
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	b.Set(v)
+	return nil
+}
+
+// MarshalText encodes the value as "true" or "false".
+func (b *Bool) MarshalText() ([]byte, error) {
+	return strconv.AppendBool(nil, b.Get()), nil
+}
+
+// UnmarshalText decodes the text encoded by MarshalText and sets the value.
+func (b *Bool) UnmarshalText(buf []byte) error {
+	v, err := strconv.ParseBool(string(buf))
+	if err != nil {
+		return err
+	}
+	b.Set(v)
+	return nil
+}
+
+// MarshalBinary encodes the value as a byte, 1 for true and 0 for false.
+func (b *Bool) MarshalBinary() ([]byte, error) {
+	if b.Get() {
+		return []byte{1}, nil
+	}
+	return []byte{0}, nil
+}
+
+// UnmarshalBinary decodes the data encoded by MarshalBinary and sets the value.
+func (b *Bool) UnmarshalBinary(buf []byte) error {
+	if len(buf) != 1 || buf[0] > 1 {
+		return fmt.Errorf("safe: invalid binary data of Bool: %v", buf)
+	}
+	b.Set(buf[0] == 1)
+	return nil
+}
+
+// GobEncode encodes the value in the same format as MarshalBinary.
+func (b *Bool) GobEncode() ([]byte, error) {
+	return b.MarshalBinary()
+}
+
+// GobDecode decodes the data encoded by GobEncode and sets the value.
+func (b *Bool) GobDecode(buf []byte) error {
+	return b.UnmarshalBinary(buf)
 }
 
+// Get gets a value atomically.
 func (b *Bool) Get() bool {
-	b.mutex.RLock()
-	v := b.value
-	b.mutex.RUnlock()
-	return v
+	return b.value.Load()
 }
 
+// Set sets a value atomically.
 func (b *Bool) Set(v bool) {
-	b.mutex.Lock()
-	b.value = v
-	b.mutex.Unlock()
+	b.value.Store(v)
+	b.notify(v)
 }
 
+// SetFunc gets a value and calls the function and sets the returned value atomically.
+// SetFunc retries with compare-and-swap until no other goroutine changes the value in the meantime,
+// so the function may be called more than once and must not have side effects.
 func (b *Bool) SetFunc(f func(v bool) bool) {
-	b.mutex.Lock()
-	b.value = f(b.value)
-	b.mutex.Unlock()
+	b.update(f)
 }
 
+// SetFuncE is like SetFunc but the value isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the value isn't updated and the panic is returned as *PanicError.
+func (b *Bool) SetFuncE(f func(v bool) (bool, error)) error {
+	f = recoverFunc(f)
+	a, err := b.updateE(f)
+	if err != nil {
+		return err
+	}
+	b.notify(a)
+	return nil
+}
+
+// Invert inverts a value atomically.
 func (b *Bool) Invert() {
-	b.mutex.Lock()
-	b.value = !b.value
-	b.mutex.Unlock()
+	b.update(invertBool)
 }
 
+// InvertR inverts a value atomically and returns the new value.
 func (b *Bool) InvertR() bool {
-	b.mutex.Lock()
-	a := !b.value
-	b.value = a
-	b.mutex.Unlock()
+	return b.update(invertBool)
+}
+
+// update sets f(old) with a compare-and-swap retry loop and returns the new value.
+func (b *Bool) update(f func(v bool) bool) bool {
+	for {
+		old := b.value.Load()
+		a := f(old)
+		if b.value.CompareAndSwap(old, a) {
+			b.notify(a)
+			return a
+		}
+	}
+}
+
+// updateE is like update but stops the retry loop when f returns an error and doesn't notify the value.
+func (b *Bool) updateE(f func(v bool) (bool, error)) (bool, error) {
+	for {
+		old := b.value.Load()
+		a, err := f(old)
+		if err != nil {
+			return false, err
+		}
+		if b.value.CompareAndSwap(old, a) {
+			return a, nil
+		}
+	}
+}
+
+func invertBool(v bool) bool {
+	return !v
+}
+
+// Swap sets a new value atomically and returns the old value.
+func (b *Bool) Swap(v bool) bool {
+	a := b.value.Swap(v)
+	b.notify(v)
 	return a
 }
+
+// CompareAndSwap sets a new value only if the current value is equal to old atomically.
+// true is returned if the value is updated.
+func (b *Bool) CompareAndSwap(old, v bool) bool {
+	ok := b.value.CompareAndSwap(old, v)
+	if !ok {
+		return false
+	}
+	b.notify(v)
+	return true
+}
+
+// notify wakes the waiters with the written value and sends the latest value to the subscribers.
+func (b *Bool) notify(v bool) {
+	b.waiters.wake(v)
+	b.watchers.notifyFunc(b.Get)
+}
+
+// WaitFor blocks until the value is equal to v or the context is done.
+// Every value written by Set, SetFunc, Invert and the other methods is checked,
+// so a value which is overwritten immediately isn't missed.
+// The error of the context is returned if the context is done before the value becomes v.
+// Changes by the Unsafe methods don't wake WaitFor.
+func (b *Bool) WaitFor(ctx context.Context, v bool) error {
+	w := b.waiters.add(b.Get, func(a bool) bool {
+		return a == v
+	})
+	_, err := b.waiters.block(ctx, w, nil)
+	return err
+}
+
+// Watch returns a channel which receives the new value after every change.
+// The value sent is loaded when the notification is sent, so a subscriber always gets the latest value eventually.
+// Watch uses WatchCoalesce, so a slow subscriber doesn't stall writers and only the latest value is kept.
+// The channel is closed when the context is done.
+// Changes by the Unsafe methods aren't notified.
+func (b *Bool) Watch(ctx context.Context) <-chan bool {
+	return b.WatchWithPolicy(ctx, WatchCoalesce, 1)
+}
+
+// WatchWithPolicy is like Watch but the policy for a slow subscriber and the buffer size of the channel can be specified.
+func (b *Bool) WatchWithPolicy(ctx context.Context, policy WatchPolicy, buffer int) <-chan bool {
+	return b.watchers.add(ctx, policy, buffer)
+}
+
+// BoolReadLocked is a handle of Bool which is passed to the function of Bool.RLock.
+// BoolReadLocked must not be used after the function returns.
+type BoolReadLocked struct {
+	value bool
+}
+
+// BoolLocked is a handle of Bool which is passed to the function of Bool.Lock.
+// BoolLocked must not be used after the function returns.
+type BoolLocked struct {
+	BoolReadLocked
+	changed bool
+}
+
+// Lock calls the function with a handle to read and write the value together without the Unsafe methods.
+// The handle works on a copy of the value, and the last value of the handle is set with compare-and-swap after the function returns.
+// Like SetFunc, if another goroutine changes the value in the meantime, the function is called again with the new value,
+// so the function may be called more than once and must not have side effects.
+// If the handle changes the value, the last value is notified to Watch and WaitFor.
+func (b *Bool) Lock(f func(v *BoolLocked)) {
+	for {
+		old := b.value.Load()
+		v := &BoolLocked{BoolReadLocked: BoolReadLocked{value: old}}
+		f(v)
+		if !v.changed {
+			return
+		}
+		if b.value.CompareAndSwap(old, v.value) {
+			b.notify(v.value)
+			return
+		}
+	}
+}
+
+// RLock calls the function with a read-only handle of the value.
+// The handle works on a copy of the value, so RLock doesn't block the other goroutines.
+func (b *Bool) RLock(f func(v *BoolReadLocked)) {
+	f(&BoolReadLocked{value: b.Get()})
+}
+
+// Get gets a value.
+func (v *BoolReadLocked) Get() bool {
+	return v.value
+}
+
+// Set sets a value.
+func (v *BoolLocked) Set(a bool) {
+	v.value = a
+	v.changed = true
+}
+
+// Invert inverts a value.
+func (v *BoolLocked) Invert() {
+	v.value = !v.value
+	v.changed = true
+}
+
+// SaveFile encodes the Bool by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (b *Bool) SaveFile(path string) error {
+	return saveFile(path, b)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (b *Bool) LoadFile(path string) error {
+	return loadFile(path, b)
+}
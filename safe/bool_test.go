@@ -1,11 +1,41 @@
 package safe
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
+	"time"
 )
 
+func newTestBool(v bool) *Bool {
+	b := &Bool{}
+	b.Set(v)
+	return b
+}
+
+// mutexBool is the former implementation of Bool, which is used only to compare the performance.
+type mutexBool struct {
+	value bool
+	mutex sync.RWMutex
+}
+
+func (b *mutexBool) Get() bool {
+	b.mutex.RLock()
+	v := b.value
+	b.mutex.RUnlock()
+	return v
+}
+
+func (b *mutexBool) Invert() {
+	b.mutex.Lock()
+	b.value = !b.value
+	b.mutex.Unlock()
+}
+
 func TestBool_String(t *testing.T) {
 	age := &Bool{}
 	var wg sync.WaitGroup
@@ -80,14 +110,63 @@ func TestBool_UnmarshalJSON(t *testing.T) {
 	}
 	exp := true
 
-	if age.value != exp {
-		t.Fatalf("Bool.UnmarshalJSON() = %t, wanted %t", age.value, exp)
+	if age.Get() != exp {
+		t.Fatalf("Bool.UnmarshalJSON() = %t, wanted %t", age.Get(), exp)
+	}
+}
+
+func TestBool_encoding(t *testing.T) {
+	flag := newTestBool(true)
+	text, err := flag.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "true" {
+		t.Fatalf("Bool.MarshalText() = %s, wanted true", text)
+	}
+	data := []struct {
+		title   string
+		marshal func(*Bool) ([]byte, error)
+		decode  func(*Bool, []byte) error
+	}{
+		{"text", (*Bool).MarshalText, (*Bool).UnmarshalText},
+		{"binary", (*Bool).MarshalBinary, (*Bool).UnmarshalBinary},
+		{"gob", func(b *Bool) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(b)
+			return buf.Bytes(), err
+		}, func(b *Bool, buf []byte) error {
+			return gob.NewDecoder(bytes.NewReader(buf)).Decode(b)
+		}},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			for _, exp := range []bool{true, false} {
+				buf, err := d.marshal(newTestBool(exp))
+				if err != nil {
+					t.Fatal(err)
+				}
+				a := newTestBool(!exp)
+				if err := d.decode(a, buf); err != nil {
+					t.Fatal(err)
+				}
+				if a.Get() != exp {
+					t.Fatalf("Bool.Get() = %t, wanted %t", a.Get(), exp)
+				}
+			}
+		})
+	}
+	if err := flag.UnmarshalBinary([]byte{2}); err == nil {
+		t.Fatal("Bool.UnmarshalBinary() must fail with invalid data")
+	}
+	if !flag.Get() {
+		t.Fatal("Bool.UnmarshalBinary() must not change the value with invalid data")
 	}
 }
 
 func TestBool_Get(t *testing.T) {
 	v := true
-	age := &Bool{value: v}
+	age := newTestBool(v)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	a := false
@@ -130,7 +209,7 @@ func TestBool_Set(t *testing.T) {
 }
 
 func TestBool_SetFunc(t *testing.T) {
-	age := &Bool{value: true}
+	age := newTestBool(true)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -153,6 +232,26 @@ func TestBool_SetFunc(t *testing.T) {
 	}
 }
 
+func TestBool_SetFuncE(t *testing.T) {
+	flag := &Bool{}
+	errAlready := errors.New("already true")
+	enable := func(v bool) (bool, error) {
+		if v {
+			return v, errAlready
+		}
+		return true, nil
+	}
+	if err := flag.SetFuncE(enable); err != nil {
+		t.Fatal(err)
+	}
+	if err := flag.SetFuncE(enable); !errors.Is(err, errAlready) {
+		t.Fatalf("Bool.SetFuncE() = %v, wanted %v", err, errAlready)
+	}
+	if a := flag.Get(); !a {
+		t.Fatalf("Bool.Get() = %t, wanted %t", a, true)
+	}
+}
+
 func TestBool_Invert(t *testing.T) {
 	age := &Bool{}
 	var wg sync.WaitGroup
@@ -192,3 +291,176 @@ func TestBool_InvertR(t *testing.T) {
 		t.Fatalf(`Bool.Get() = %t, wanted %t`, a, exp)
 	}
 }
+
+func BenchmarkBool_Get_parallel(b *testing.B) {
+	b.Run("atomic", func(b *testing.B) {
+		flag := &Bool{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				flag.Get()
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		flag := &mutexBool{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				flag.Get()
+			}
+		})
+	})
+}
+
+func BenchmarkBool_Invert_parallel(b *testing.B) {
+	b.Run("atomic", func(b *testing.B) {
+		flag := &Bool{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				flag.Invert()
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		flag := &mutexBool{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				flag.Invert()
+			}
+		})
+	})
+}
+
+func TestBool_Swap(t *testing.T) {
+	age := newTestBool(true)
+	if a := age.Swap(false); !a {
+		t.Fatalf("Bool.Swap() = %t, wanted %t", a, true)
+	}
+	if a := age.Get(); a {
+		t.Fatalf("Bool.Get() = %t, wanted %t", a, false)
+	}
+}
+
+func TestBool_CompareAndSwap(t *testing.T) {
+	age := &Bool{}
+	var wg sync.WaitGroup
+	var swapped Int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			if age.CompareAndSwap(false, true) {
+				swapped.Add(1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := swapped.Get(); a != 1 {
+		t.Fatalf("the number of successful CompareAndSwap = %d, wanted 1", a)
+	}
+	if a := age.Get(); !a {
+		t.Fatalf("Bool.Get() = %t, wanted %t", a, true)
+	}
+}
+
+func TestBool_Watch(t *testing.T) {
+	flag := &Bool{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := flag.Watch(ctx)
+	flag.Set(true)
+	if a := receiveWithTimeout(t, ch); !a {
+		t.Fatalf("received %t, wanted %t", a, true)
+	}
+	flag.Invert()
+	if a := receiveWithTimeout(t, ch); a {
+		t.Fatalf("received %t, wanted %t", a, false)
+	}
+	if err := json.Unmarshal([]byte("true"), flag); err != nil {
+		t.Fatal(err)
+	}
+	if a := receiveWithTimeout(t, ch); !a {
+		t.Fatalf("received %t, wanted %t", a, true)
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestBool_WaitFor(t *testing.T) {
+	flag := &Bool{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := flag.WaitFor(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if err := flag.WaitFor(ctx, true); err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}()
+	flag.Invert()
+	wg.Wait()
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	if err := flag.WaitFor(ctx2, false); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Bool.WaitFor() = %v, wanted %v", err, context.Canceled)
+	}
+}
+
+func TestBool_Lock(t *testing.T) {
+	flag := &Bool{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		for i := 0; i < 100; i++ {
+			flag.Invert()
+		}
+		wg.Done()
+	}()
+	go func() {
+		for i := 0; i < 100; i++ {
+			flag.Lock(func(v *BoolLocked) {
+				v.Set(!v.Get())
+				v.Invert()
+				v.Invert()
+			})
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := flag.Get(); a {
+		t.Fatalf("Bool.Get() = %t, wanted %t", a, false)
+	}
+	flag.RLock(func(v *BoolReadLocked) {
+		if v.Get() {
+			t.Fatalf("BoolReadLocked.Get() = %t, wanted %t", true, false)
+		}
+	})
+}
+
+func TestBool_Lock_notify(t *testing.T) {
+	flag := &Bool{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch := flag.Watch(ctx)
+	done := make(chan error)
+	go func() {
+		done <- flag.WaitFor(ctx, true)
+	}()
+	for flag.waiters.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	flag.Lock(func(v *BoolLocked) {
+		v.Invert()
+	})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if a := receiveWithTimeout(t, ch); !a {
+		t.Fatalf("received %t, wanted %t", a, true)
+	}
+}
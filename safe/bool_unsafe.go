@@ -1,11 +1,14 @@
 package safe
 
+// Bool is lock-free, so the Unsafe methods of Bool are still atomic individually.
+// They don't notify Watch and WaitFor.
+
 // GetUnsafe gets a value without lock.
 func (b *Bool) GetUnsafe() bool {
-	return b.value
+	return b.value.Load()
 }
 
 // SetUnsafe sets a value without lock.
 func (b *Bool) SetUnsafe(v bool) {
-	b.value = v
+	b.value.Store(v)
 }
@@ -6,7 +6,7 @@ import (
 
 func TestBool_GetUnsafe(t *testing.T) {
 	v := true
-	age := &Bool{value: v}
+	age := newTestBool(v)
 	a := age.GetUnsafe()
 	if a != v {
 		t.Fatalf("Bool.GetUnsafe() = %t, wanted %t", a, v)
@@ -17,7 +17,7 @@ func TestBool_SetUnsafe(t *testing.T) {
 	v := true
 	age := &Bool{}
 	age.SetUnsafe(v)
-	if age.value != v {
-		t.Fatalf("Bool.GetUnsafe() = %t, wanted %t", age.value, v)
+	if age.Get() != v {
+		t.Fatalf("Bool.GetUnsafe() = %t, wanted %t", age.Get(), v)
 	}
 }
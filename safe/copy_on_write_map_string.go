@@ -0,0 +1,276 @@
+package safe
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// CopyOnWriteMapString wraps map[string]string and is optimized for the read heavy workload.
+// Readers load an immutable snapshot of the map through an atomic pointer without lock.
+// Writers are serialized by a mutex and publish a new map, which copies the current snapshot and applies the change.
+// So each write costs O(n), and CopyOnWriteMapString fits the map which is rarely updated.
+//
+// CopyOnWriteMapString must be used as the pointer because CopyOnWriteMapString has sync.RWMutex as a private field.
+// The zero value is an empty map.
+type CopyOnWriteMapString struct {
+	value atomic.Pointer[map[string]string]
+	mutex rwMutex
+}
+
+// NewCopyOnWriteMapString creates a CopyOnWriteMapString.
+// Note that the argument `value` is holden in CopyOnWriteMapString as the first snapshot,
+// so don't read and write `value` out of the CopyOnWriteMapString.
+func NewCopyOnWriteMapString(value map[string]string) *CopyOnWriteMapString {
+	m := &CopyOnWriteMapString{}
+	m.value.Store(&value)
+	return m
+}
+
+// snapshot returns the current map.
+// The returned map must not be modified.
+func (m *CopyOnWriteMapString) snapshot() map[string]string {
+	if p := m.value.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// update copies the current map and calls the function and publishes the copied map.
+// If the function returns false, the copied map is discarded.
+func (m *CopyOnWriteMapString) update(f func(value map[string]string) bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	current := m.snapshot()
+	copiedM := make(map[string]string, len(current)+1)
+	for k, v := range current {
+		copiedM[k] = v
+	}
+	if f(copiedM) {
+		m.value.Store(&copiedM)
+	}
+}
+
+func (m *CopyOnWriteMapString) String() string {
+	return "CopyOnWriteMapString{" + fmt.Sprintf("%v", m.snapshot()) + "}"
+}
+
+func (m *CopyOnWriteMapString) MarshalJSON() ([]byte, error) {
+	v := m.snapshot()
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(v)
+}
+
+func (m *CopyOnWriteMapString) UnmarshalJSON(buf []byte) error {
+	var v map[string]string
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	m.update(func(value map[string]string) bool {
+		for k, a := range v {
+			value[k] = a
+		}
+		return true
+	})
+	return nil
+}
+
+// Get gets a value from the map without lock.
+func (m *CopyOnWriteMapString) Get(k string) string {
+	return m.snapshot()[k]
+}
+
+// GetOk gets a value from the map without lock.
+func (m *CopyOnWriteMapString) GetOk(k string) (string, bool) {
+	v, ok := m.snapshot()[k]
+	return v, ok
+}
+
+// Has checks whether the map has the key without lock.
+func (m *CopyOnWriteMapString) Has(k string) bool {
+	_, ok := m.snapshot()[k]
+	return ok
+}
+
+// Len gets the length of the map without lock.
+func (m *CopyOnWriteMapString) Len() int {
+	return len(m.snapshot())
+}
+
+// Delete deletes the key from the map.
+func (m *CopyOnWriteMapString) Delete(k string) {
+	m.DeleteROk(k)
+}
+
+// DeleteR deletes the key from the map and returns the value.
+func (m *CopyOnWriteMapString) DeleteR(k string) string {
+	v, _ := m.DeleteROk(k)
+	return v
+}
+
+// DeleteROk deletes the key from the map and returns the value.
+// If the map doesn't have the key, no new map is published.
+func (m *CopyOnWriteMapString) DeleteROk(k string) (string, bool) {
+	var v string
+	var ok bool
+	m.update(func(value map[string]string) bool {
+		v, ok = value[k]
+		delete(value, k)
+		return ok
+	})
+	return v, ok
+}
+
+// Set sets the key and value to the map.
+func (m *CopyOnWriteMapString) Set(k, v string) {
+	m.update(func(value map[string]string) bool {
+		value[k] = v
+		return true
+	})
+}
+
+// SetDefault sets the key and value to the map if the map doesn't have the key.
+func (m *CopyOnWriteMapString) SetDefault(k, v string) {
+	m.SetDefaultR(k, v)
+}
+
+// SetDefaultR sets the key and value to the map if the map doesn't have the key and returns the value.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (m *CopyOnWriteMapString) SetDefaultR(k, v string) (string, bool) {
+	if a, ok := m.GetOk(k); ok {
+		return a, true
+	}
+	a := v
+	var ok bool
+	m.update(func(value map[string]string) bool {
+		if a, ok = value[k]; ok {
+			return false
+		}
+		value[k] = v
+		a = v
+		return true
+	})
+	return a, ok
+}
+
+// SetFunc gets a value of the key from the map and calls the function and sets the returned value to the map.
+// This is used to update the value based on the original value atomicaly.
+func (m *CopyOnWriteMapString) SetFunc(k string, f func(string, bool) string) {
+	m.update(func(value map[string]string) bool {
+		v, ok := value[k]
+		value[k] = f(v, ok)
+		return true
+	})
+}
+
+// SetFuncE is like SetFunc but the map isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the map isn't updated and the panic is returned as *PanicError.
+func (m *CopyOnWriteMapString) SetFuncE(k string, f func(string, bool) (string, error)) error {
+	f = recoverMapFunc(f)
+	var err error
+	m.update(func(value map[string]string) bool {
+		v, ok := value[k]
+		var a string
+		if a, err = f(v, ok); err != nil {
+			return false
+		}
+		value[k] = a
+		return true
+	})
+	return err
+}
+
+// Swap sets the key and value to the map and returns the previous value.
+// true is returned if the map has had the key.
+func (m *CopyOnWriteMapString) Swap(k, v string) (string, bool) {
+	var a string
+	var ok bool
+	m.update(func(value map[string]string) bool {
+		a, ok = value[k]
+		value[k] = v
+		return true
+	})
+	return a, ok
+}
+
+// CompareAndSwap sets the value of the key only if the map has the key and the current value is equal to old.
+// true is returned if the value is updated.
+func (m *CopyOnWriteMapString) CompareAndSwap(k, old, v string) bool {
+	var swapped bool
+	m.update(func(value map[string]string) bool {
+		if a, ok := value[k]; !ok || a != old {
+			return false
+		}
+		value[k] = v
+		swapped = true
+		return true
+	})
+	return swapped
+}
+
+// CompareAndDelete deletes the key only if the current value is equal to old.
+// true is returned if the key is deleted.
+func (m *CopyOnWriteMapString) CompareAndDelete(k, old string) bool {
+	var deleted bool
+	m.update(func(value map[string]string) bool {
+		if a, ok := value[k]; !ok || a != old {
+			return false
+		}
+		delete(value, k)
+		deleted = true
+		return true
+	})
+	return deleted
+}
+
+// Range calls the function for all pairs of the key and value in the current snapshot.
+// The snapshot isn't copied, and changes during the iteration aren't visible to the function.
+func (m *CopyOnWriteMapString) Range(f func(k, v string)) {
+	for k, v := range m.snapshot() {
+		f(k, v)
+	}
+}
+
+// RangeB calls the function for all pairs of the key and value in the current snapshot.
+// The snapshot isn't copied, and changes during the iteration aren't visible to the function.
+// If the function returns false, the loop ends.
+func (m *CopyOnWriteMapString) RangeB(f func(k, v string) bool) {
+	for k, v := range m.snapshot() {
+		if !f(k, v) {
+			break
+		}
+	}
+}
+
+// Copy copies all pairs of the key and value to target.
+func (m *CopyOnWriteMapString) Copy(target *CopyOnWriteMapString) {
+	src := m.snapshot()
+	target.update(func(value map[string]string) bool {
+		for k, v := range src {
+			value[k] = v
+		}
+		return true
+	})
+}
+
+// CopyData copies the current snapshot to target.
+func (m *CopyOnWriteMapString) CopyData(target map[string]string) {
+	for k, v := range m.snapshot() {
+		target[k] = v
+	}
+}
+
+// SaveFile encodes the CopyOnWriteMapString by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (m *CopyOnWriteMapString) SaveFile(path string) error {
+	return saveFile(path, m)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (m *CopyOnWriteMapString) LoadFile(path string) error {
+	return loadFile(path, m)
+}
@@ -0,0 +1,279 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCopyOnWriteMapString_String(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Set("zoo", "world")
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "CopyOnWriteMapString{map[foo:bar zoo:world]}"
+	if a != exp {
+		t.Fatalf("CopyOnWriteMapString.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestCopyOnWriteMapString_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(&CopyOnWriteMapString{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "{}" {
+		t.Fatalf("CopyOnWriteMapString.MarshalJSON() = %s, wanted %s", string(b), "{}")
+	}
+
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set("foo", "world")
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"foo":"world"}`
+	if string(b) != exp {
+		t.Fatalf("CopyOnWriteMapString.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestCopyOnWriteMapString_UnmarshalJSON(t *testing.T) {
+	age := &CopyOnWriteMapString{}
+	var wg sync.WaitGroup
+	buf := []byte(`{"hello":"world"}`)
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set("zoo", "world")
+		wg.Done()
+	}()
+	go func() {
+		err = json.Unmarshal(buf, age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := age.Len(); a != 2 {
+		t.Fatalf("CopyOnWriteMapString.Len() = %d, wanted %d", a, 2)
+	}
+	if a := age.Get("hello"); a != "world" {
+		t.Fatalf(`CopyOnWriteMapString.Get("hello") = %s, wanted %s`, a, "world")
+	}
+}
+
+func TestCopyOnWriteMapString_Get(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Get("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", "zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Get("foo"); a != "zoo" {
+		t.Fatalf("CopyOnWriteMapString.Get() = %s, wanted %s", a, "zoo")
+	}
+	if a, ok := age.GetOk("bar"); a != "" || ok {
+		t.Fatalf("CopyOnWriteMapString.GetOk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if !age.Has("foo") {
+		t.Fatalf("CopyOnWriteMapString.Has() = %t, wanted %t", false, true)
+	}
+}
+
+func TestCopyOnWriteMapString_Set(t *testing.T) {
+	age := &CopyOnWriteMapString{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			age.Set(strconv.Itoa(i), "")
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Len(); a != 10 {
+		t.Fatalf("CopyOnWriteMapString.Len() = %d, wanted %d", a, 10)
+	}
+}
+
+func TestCopyOnWriteMapString_Delete(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar", "zoo": "world", "hello": "world"})
+	age.Delete("foo")
+	if a := age.DeleteR("zoo"); a != "world" {
+		t.Fatalf("CopyOnWriteMapString.DeleteR() = %s, wanted %s", a, "world")
+	}
+	if a, ok := age.DeleteROk("hello"); a != "world" || !ok {
+		t.Fatalf("CopyOnWriteMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "world", true)
+	}
+	if a, ok := age.DeleteROk("hello"); a != "" || ok {
+		t.Fatalf("CopyOnWriteMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if a := age.Len(); a != 0 {
+		t.Fatalf("CopyOnWriteMapString.Len() = %d, wanted %d", a, 0)
+	}
+}
+
+func TestCopyOnWriteMapString_SetDefault(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	age.SetDefault("foo", "zoo")
+	if a := age.Get("foo"); a != "bar" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "bar")
+	}
+	if a, ok := age.SetDefaultR("zoo", "world"); a != "world" || ok {
+		t.Fatalf(`age.SetDefaultR("zoo") = %s, %t, wanted %s, %t`, a, ok, "world", false)
+	}
+	if a, ok := age.SetDefaultR("zoo", "hello"); a != "world" || !ok {
+		t.Fatalf(`age.SetDefaultR("zoo") = %s, %t, wanted %s, %t`, a, ok, "world", true)
+	}
+}
+
+func TestCopyOnWriteMapString_SetFunc(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			age.SetFunc("foo", func(v string, ok bool) string {
+				return v + "!"
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Get("foo"); a != "bar!!" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "bar!!")
+	}
+}
+
+func TestCopyOnWriteMapString_SetFuncE(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"state": "draft"})
+	errTransition := errors.New("invalid transition")
+	publish := func(v string, ok bool) (string, error) {
+		if v != "draft" {
+			return "", errTransition
+		}
+		return "published", nil
+	}
+	if err := age.SetFuncE("state", publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE("state", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("CopyOnWriteMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if a := age.Get("state"); a != "published" {
+		t.Fatalf(`CopyOnWriteMapString.Get("state") = %s, wanted published`, a)
+	}
+	if err := age.SetFuncE("foo", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("CopyOnWriteMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if age.Has("foo") {
+		t.Fatal(`the key "foo" must not be added`)
+	}
+}
+
+func TestCopyOnWriteMapString_CompareAndSwap(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	if age.CompareAndSwap("foo", "zoo", "world") {
+		t.Fatal("CompareAndSwap must fail if the value isn't equal to old")
+	}
+	if !age.CompareAndSwap("foo", "bar", "world") {
+		t.Fatal("CompareAndSwap must succeed if the value is equal to old")
+	}
+	if a, _ := age.Swap("foo", "hello"); a != "world" {
+		t.Fatalf(`age.Swap("foo") = %s, wanted %s`, a, "world")
+	}
+	if age.CompareAndDelete("foo", "world") {
+		t.Fatal("CompareAndDelete must fail if the value isn't equal to old")
+	}
+	if !age.CompareAndDelete("foo", "hello") {
+		t.Fatal("CompareAndDelete must succeed if the value is equal to old")
+	}
+}
+
+func TestCopyOnWriteMapString_Range(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	cnt := 0
+	age.Range(func(k, v string) {
+		cnt++
+		// the change isn't visible to the running iteration.
+		age.Set("zoo", "world")
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+	cnt = 0
+	age.RangeB(func(k, v string) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
+
+func TestCopyOnWriteMapString_Copy(t *testing.T) {
+	age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	cp := &CopyOnWriteMapString{}
+	age.Copy(cp)
+	if a := cp.Get("foo"); a != "bar" {
+		t.Fatalf(`cp.Get("foo") = %s, wanted %s`, a, "bar")
+	}
+	data := map[string]string{}
+	age.CopyData(data)
+	if a := len(data); a != 1 {
+		t.Fatalf("len(data) = %d, wanted %d", a, 1)
+	}
+}
+
+func BenchmarkCopyOnWriteMapString_Get_parallel(b *testing.B) {
+	b.Run("CopyOnWriteMapString", func(b *testing.B) {
+		age := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				age.Get("foo")
+			}
+		})
+	})
+	b.Run("MapString", func(b *testing.B) {
+		age := NewMapString(map[string]string{"foo": "bar"})
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				age.Get("foo")
+			}
+		})
+	})
+}
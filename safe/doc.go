@@ -3,10 +3,34 @@ Package safe provides API for thread safe data operation.
 safe provides some struct which has a data internally.
 These structs have some methods to do thead safe operation to their internal data.
 Internally sync.RWMutex is used for thread safe operation.
+Bool and Int are exceptions, they use sync/atomic and are lock-free.
 
 The methods whose name ends with `Unsafe` operates internal data without lock,
 which means these methods aren't thread safe.
 We should use these methods carefully.
 Note that they don't have nothing to do with the standard library "unsafe".
+To read and write a container with its own lock, Bool, Int, String, Map and MapString provide the methods `Lock` and `RLock`,
+which pass a handle to operate the data only in the callback.
+The handles of Bool and Int work on a copy of the value, which is set with compare-and-swap like SetFunc.
+To read and write some containers together, lock them by LockAll or Atomically and use the Unsafe methods.
+Bool and Int can't be locked, so use Value[bool] and Value[int] to change them together with other containers.
+The changes made while they are locked wake the wait methods such as Map.WaitForKey when they are unlocked, but they aren't notified to Watch.
+
+The methods which take a callback release the lock even if the callback panics.
+Recover calls a function and returns the panic as an error, so a bad callback doesn't take down the process.
+SetFuncE returns a panic in its callback as *PanicError, so Recover isn't needed for it.
+
+The locks aren't reentrant, so a callback which calls a method of the container running the callback deadlocks.
+With the build tag `safedebug`, the containers detect it and panic with the names of both methods instead of hanging.
+This is slow, so use it only for testing and debugging.
+
+	go test -tags safedebug ./...
+
+Bool, Int, String, Map and MapString can be watched by the method `Watch`,
+which returns a channel to receive changes.
+The changes by the methods whose name ends with `Unsafe` aren't notified.
+
+The containers can be saved to a file by SaveFile and Snapshot.
+DurableMapString appends every change to a write-ahead log before returning, so no acknowledged change is lost by a crash.
 */
 package safe
@@ -0,0 +1,429 @@
+package safe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WALSyncPolicy decides when the write-ahead log of DurableMapString is flushed to the disk by fsync.
+type WALSyncPolicy int
+
+const (
+	// WALSyncAlways calls fsync after every change before the method returns.
+	// No acknowledged change is lost even if the OS crashes, but each change waits for the disk.
+	WALSyncAlways WALSyncPolicy = iota
+	// WALSyncBatch calls fsync periodically in the background.
+	// Changes in the last interval may be lost if the OS crashes.
+	WALSyncBatch
+	// WALSyncNone never calls fsync and leaves flushing to the OS.
+	// Changes survive a crash of the process but may be lost if the OS crashes.
+	WALSyncNone
+)
+
+// defaultWALSyncInterval is the interval of fsync with WALSyncBatch.
+const defaultWALSyncInterval = 100 * time.Millisecond
+
+// DurableMapStringOption is the option of OpenDurableMapString.
+type DurableMapStringOption struct {
+	// Sync is the fsync policy of the write-ahead log. The default is WALSyncAlways.
+	Sync WALSyncPolicy
+	// SyncInterval is the interval of fsync with WALSyncBatch. The default is 100ms.
+	SyncInterval time.Duration
+}
+
+// DurableMapString is a map[string]string which persists every change to a local file.
+// Each change by Set, Delete and SetFunc is appended to a write-ahead log before the method returns.
+// OpenDurableMapString loads the snapshot and replays the log, so the map survives a crash.
+// Compact folds the log into a fresh snapshot.
+//
+// The snapshot is written to path in the same format as SaveFile, and the log is written to path + ".wal".
+// DurableMapString must be created by OpenDurableMapString and closed by Close.
+type DurableMapString struct {
+	value   map[string]string
+	path    string
+	wal     walFile
+	walSize int64
+	policy  WALSyncPolicy
+	mutex   rwMutex
+	syncErr error
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// walFile is the file of the write-ahead log.
+// This is an interface so that tests can inject write errors.
+type walFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(size int64) error
+}
+
+// walRecord is a change recorded in the write-ahead log.
+// Each record is written as a line of the CRC-32 checksum in hex and the JSON encoded record.
+type walRecord struct {
+	Op    string `json:"op"`
+	Key   string `json:"k"`
+	Value string `json:"v,omitempty"`
+}
+
+const (
+	walOpSet    = "set"
+	walOpDelete = "delete"
+)
+
+// OpenDurableMapString opens the snapshot and the write-ahead log at path and restores the map.
+// If the files don't exist, an empty map is created.
+// An incomplete record at the end of the log, which is left by a crash while writing, is discarded.
+// ErrCorruptFile is returned if the snapshot or the other records of the log are broken.
+func OpenDurableMapString(path string, opt DurableMapStringOption) (*DurableMapString, error) {
+	m := &DurableMapString{
+		value:  map[string]string{},
+		path:   path,
+		policy: opt.Sync,
+	}
+	data, err := readFileData(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &m.value); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrCorruptFile, path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+	if err := m.replay(); err != nil {
+		return nil, err
+	}
+	wal, err := os.OpenFile(m.walPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, filePerm)
+	if err != nil {
+		return nil, err
+	}
+	// flush the log truncated by replay and the directory entry of the created log,
+	// or an OS crash may lose the changes acknowledged after this.
+	if err := wal.Sync(); err != nil {
+		wal.Close() //nolint:errcheck
+		return nil, err
+	}
+	syncDir(filepath.Dir(path))
+	fi, err := wal.Stat()
+	if err != nil {
+		wal.Close() //nolint:errcheck
+		return nil, err
+	}
+	m.wal = wal
+	m.walSize = fi.Size()
+	if m.policy == WALSyncBatch {
+		interval := opt.SyncInterval
+		if interval <= 0 {
+			interval = defaultWALSyncInterval
+		}
+		m.stop = make(chan struct{})
+		m.stopped.Add(1)
+		go m.syncPeriodically(m.stop, interval)
+	}
+	return m, nil
+}
+
+func (m *DurableMapString) walPath() string {
+	return m.path + ".wal"
+}
+
+// replay applies the records of the log to the map.
+// An incomplete record at the end of the log is truncated.
+func (m *DurableMapString) replay() error {
+	buf, err := os.ReadFile(m.walPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	offset := 0
+	for offset < len(buf) {
+		i := bytes.IndexByte(buf[offset:], '\n')
+		if i < 0 {
+			// the last record is incomplete because of a crash while writing.
+			return os.Truncate(m.walPath(), int64(offset))
+		}
+		rec, err := decodeWALRecord(buf[offset : offset+i])
+		if err != nil {
+			return fmt.Errorf("%w: %s: offset %d: %v", ErrCorruptFile, m.walPath(), offset, err)
+		}
+		m.applyUnsafe(rec)
+		offset += i + 1
+	}
+	return nil
+}
+
+func encodeWALRecord(rec walRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	line := fmt.Appendf(nil, "%08x ", crc32.ChecksumIEEE(payload))
+	line = append(line, payload...)
+	return append(line, '\n'), nil
+}
+
+func decodeWALRecord(line []byte) (walRecord, error) {
+	var rec walRecord
+	sum, payload, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return rec, errors.New("no checksum")
+	}
+	expected, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil {
+		return rec, err
+	}
+	if crc32.ChecksumIEEE(payload) != uint32(expected) {
+		return rec, errors.New("checksum mismatch")
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, err
+	}
+	if rec.Op != walOpSet && rec.Op != walOpDelete {
+		return rec, fmt.Errorf("unknown operation %q", rec.Op)
+	}
+	return rec, nil
+}
+
+func (m *DurableMapString) applyUnsafe(rec walRecord) {
+	if rec.Op == walOpDelete {
+		delete(m.value, rec.Key)
+		return
+	}
+	m.value[rec.Key] = rec.Value
+}
+
+// appendUnsafe writes the record to the log and applies it to the map.
+// The map isn't changed if the record can't be written,
+// and the log is truncated to the end of the previous record so that a partial record doesn't break the following records.
+// If fsync fails, the following changes fail too because the data written before may not be on the disk.
+// The caller must hold the lock.
+func (m *DurableMapString) appendUnsafe(rec walRecord) error {
+	if m.wal == nil {
+		return os.ErrClosed
+	}
+	if m.syncErr != nil {
+		return m.syncErr
+	}
+	line, err := encodeWALRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := m.wal.Write(line); err != nil {
+		m.rollbackUnsafe()
+		return err
+	}
+	if m.policy == WALSyncAlways {
+		if err := m.wal.Sync(); err != nil {
+			m.rollbackUnsafe()
+			if m.syncErr == nil {
+				m.syncErr = err
+			}
+			return err
+		}
+	}
+	m.walSize += int64(len(line))
+	m.applyUnsafe(rec)
+	return nil
+}
+
+// rollbackUnsafe truncates the log to the end of the last record which was written successfully.
+// If the log can't be truncated, the following changes fail with the error.
+// The caller must hold the lock.
+func (m *DurableMapString) rollbackUnsafe() {
+	if err := m.wal.Truncate(m.walSize); err != nil && m.syncErr == nil {
+		m.syncErr = err
+	}
+}
+
+func (m *DurableMapString) syncPeriodically(stop <-chan struct{}, interval time.Duration) {
+	defer m.stopped.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := m.Sync(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (m *DurableMapString) String() string {
+	m.mutex.RLock()
+	v := "DurableMapString{" + fmt.Sprintf("%v", m.value) + "}"
+	m.mutex.RUnlock()
+	return v
+}
+
+func (m *DurableMapString) MarshalJSON() ([]byte, error) {
+	m.mutex.RLock()
+	b, err := json.Marshal(m.value)
+	m.mutex.RUnlock()
+	return b, err
+}
+
+// Get gets a value from the map with lock.
+func (m *DurableMapString) Get(k string) string {
+	m.mutex.RLock()
+	v := m.value[k]
+	m.mutex.RUnlock()
+	return v
+}
+
+// GetOk gets a value from the map with lock.
+func (m *DurableMapString) GetOk(k string) (string, bool) {
+	m.mutex.RLock()
+	v, ok := m.value[k]
+	m.mutex.RUnlock()
+	return v, ok
+}
+
+// Has checks whether the map has the key with lock.
+func (m *DurableMapString) Has(k string) bool {
+	m.mutex.RLock()
+	_, ok := m.value[k]
+	m.mutex.RUnlock()
+	return ok
+}
+
+// Len gets the length of the map with lock.
+func (m *DurableMapString) Len() int {
+	m.mutex.RLock()
+	v := len(m.value)
+	m.mutex.RUnlock()
+	return v
+}
+
+// Set writes the change to the log and sets the key and value to the map with lock.
+// If the change can't be written, the map isn't changed and the error is returned.
+func (m *DurableMapString) Set(k, v string) error {
+	m.mutex.Lock()
+	err := m.appendUnsafe(walRecord{Op: walOpSet, Key: k, Value: v})
+	m.mutex.Unlock()
+	return err
+}
+
+// Delete writes the change to the log and deletes the key from the map with lock.
+// Nothing is written if the map doesn't have the key.
+// If the change can't be written, the map isn't changed and the error is returned.
+func (m *DurableMapString) Delete(k string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.value[k]; !ok {
+		return nil
+	}
+	return m.appendUnsafe(walRecord{Op: walOpDelete, Key: k})
+}
+
+// SetFunc gets a value of the key from the map and calls the function,
+// and writes the returned value to the log and sets it to the map with lock.
+// If the change can't be written, the map isn't changed and the error is returned.
+func (m *DurableMapString) SetFunc(k string, f func(string, bool) string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, ok := m.value[k]
+	return m.appendUnsafe(walRecord{Op: walOpSet, Key: k, Value: f(v, ok)})
+}
+
+// Range gets all pairs of the key and value from the map with lock and calls the function.
+func (m *DurableMapString) Range(f func(k, v string)) {
+	m.mutex.RLock()
+	copiedM := make(map[string]string, len(m.value))
+	for k, v := range m.value {
+		copiedM[k] = v
+	}
+	m.mutex.RUnlock()
+	for k, v := range copiedM {
+		f(k, v)
+	}
+}
+
+// CopyData copies all pairs of the key and value to target.
+func (m *DurableMapString) CopyData(target map[string]string) {
+	m.mutex.RLock()
+	for k, v := range m.value {
+		target[k] = v
+	}
+	m.mutex.RUnlock()
+}
+
+// Sync flushes the log to the disk by fsync.
+// With WALSyncBatch, Sync is called periodically.
+// If fsync fails in the background, the following changes return the error.
+func (m *DurableMapString) Sync() error {
+	// fsync doesn't need the exclusive lock, so readers aren't blocked.
+	m.mutex.RLock()
+	if m.wal == nil {
+		m.mutex.RUnlock()
+		return os.ErrClosed
+	}
+	err := m.wal.Sync()
+	m.mutex.RUnlock()
+	if err != nil {
+		m.mutex.Lock()
+		if m.syncErr == nil {
+			m.syncErr = err
+		}
+		m.mutex.Unlock()
+	}
+	return err
+}
+
+// Compact writes the map to the snapshot file atomically and truncates the log with lock.
+// If the process crashes after the snapshot is written but before the log is truncated,
+// the log is replayed over the new snapshot, which yields the same map.
+func (m *DurableMapString) Compact() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.wal == nil {
+		return os.ErrClosed
+	}
+	if err := saveFile(m.path, m.value); err != nil {
+		return err
+	}
+	if err := m.wal.Truncate(0); err != nil {
+		return err
+	}
+	m.walSize = 0
+	return m.wal.Sync()
+}
+
+// Close flushes and closes the log.
+// The map can't be changed after Close, and Close returns os.ErrClosed if the map is already closed.
+func (m *DurableMapString) Close() error {
+	// the background goroutine takes the read lock to call Sync,
+	// so it is stopped without the lock.
+	m.mutex.Lock()
+	stop := m.stop
+	m.stop = nil
+	m.mutex.Unlock()
+	if stop != nil {
+		close(stop)
+		m.stopped.Wait()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.wal == nil {
+		return os.ErrClosed
+	}
+	err := m.wal.Sync()
+	if cerr := m.wal.Close(); err == nil {
+		err = cerr
+	}
+	m.wal = nil
+	return err
+}
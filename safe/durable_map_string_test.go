@@ -0,0 +1,270 @@
+package safe
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openTestDurableMapString(t *testing.T, path string, opt DurableMapStringOption) *DurableMapString {
+	t.Helper()
+	m, err := OpenDurableMapString(path, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDurableMapString(t *testing.T) {
+	data := []struct {
+		title string
+		opt   DurableMapStringOption
+	}{
+		{"always", DurableMapStringOption{Sync: WALSyncAlways}},
+		{"batch", DurableMapStringOption{Sync: WALSyncBatch, SyncInterval: time.Millisecond}},
+		{"none", DurableMapStringOption{Sync: WALSyncNone}},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "names.json")
+			m := openTestDurableMapString(t, path, d.opt)
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					if err := m.Set(strconv.Itoa(i), "foo"); err != nil {
+						t.Error(err)
+					}
+					wg.Done()
+				}()
+			}
+			wg.Wait()
+			if err := m.Delete("0"); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.SetFunc("1", func(v string, ok bool) string {
+				return v + strconv.FormatBool(ok)
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Close(); !errors.Is(err, os.ErrClosed) {
+				t.Fatalf("DurableMapString.Close() = %v, wanted %v", err, os.ErrClosed)
+			}
+			if err := m.Set("foo", "bar"); !errors.Is(err, os.ErrClosed) {
+				t.Fatalf("DurableMapString.Set() = %v, wanted %v", err, os.ErrClosed)
+			}
+
+			reopened := openTestDurableMapString(t, path, d.opt)
+			defer reopened.Close()
+			exp := map[string]string{}
+			m.CopyData(exp)
+			a := map[string]string{}
+			reopened.CopyData(a)
+			if !reflect.DeepEqual(a, exp) {
+				t.Fatalf("OpenDurableMapString() = %v, wanted %v", a, exp)
+			}
+			if a := reopened.Get("1"); a != "footrue" {
+				t.Fatalf(`DurableMapString.Get("1") = %s, wanted footrue`, a)
+			}
+			if reopened.Has("0") {
+				t.Fatal(`DurableMapString.Has("0") = true, wanted false`)
+			}
+		})
+	}
+}
+
+func TestDurableMapString_torn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.json")
+	m := openTestDurableMapString(t, path, DurableMapStringOption{})
+	if err := m.Set("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// simulate a crash while the record is written.
+	line, err := encodeWALRecord(walRecord{Op: walOpSet, Key: "hello", Value: "world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path+".wal", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(line[:len(line)/2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m = openTestDurableMapString(t, path, DurableMapStringOption{})
+	if a := m.String(); a != "DurableMapString{map[foo:bar]}" {
+		t.Fatalf("OpenDurableMapString() = %s, wanted DurableMapString{map[foo:bar]}", a)
+	}
+	// the incomplete record must be truncated so that the next record is readable.
+	if err := m.Set("hello", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m = openTestDurableMapString(t, path, DurableMapStringOption{})
+	defer m.Close()
+	if a := m.String(); a != "DurableMapString{map[foo:bar hello:world]}" {
+		t.Fatalf("OpenDurableMapString() = %s, wanted DurableMapString{map[foo:bar hello:world]}", a)
+	}
+}
+
+func TestDurableMapString_corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.json")
+	m := openTestDurableMapString(t, path, DurableMapStringOption{})
+	if err := m.Set("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("hello", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(path + ".wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// modify the value of the first record.
+	buf[bytes.Index(buf, []byte("bar"))] = 'z'
+	if err := os.WriteFile(path+".wal", buf, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDurableMapString(path, DurableMapStringOption{}); !errors.Is(err, ErrCorruptFile) {
+		t.Fatalf("OpenDurableMapString() = %v, wanted %v", err, ErrCorruptFile)
+	}
+}
+
+func TestDurableMapString_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.json")
+	m := openTestDurableMapString(t, path, DurableMapStringOption{})
+	for i := 0; i < 10; i++ {
+		if err := m.Set("foo", strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path + ".wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := info.Size(); a != 0 {
+		t.Fatalf("the size of the log = %d, wanted %d", a, 0)
+	}
+	if err := m.Set("hello", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m = openTestDurableMapString(t, path, DurableMapStringOption{})
+	defer m.Close()
+	if a := m.String(); a != "DurableMapString{map[foo:9 hello:world]}" {
+		t.Fatalf("OpenDurableMapString() = %s, wanted DurableMapString{map[foo:9 hello:world]}", a)
+	}
+}
+
+// failingWAL is a walFile which fails to write or sync.
+// A failed Write writes the first half of the data like a short write.
+type failingWAL struct {
+	walFile
+	writeErr error
+	syncErr  error
+}
+
+func (w *failingWAL) Write(p []byte) (int, error) {
+	if w.writeErr == nil {
+		return w.walFile.Write(p)
+	}
+	n, _ := w.walFile.Write(p[:len(p)/2])
+	return n, w.writeErr
+}
+
+func (w *failingWAL) Sync() error {
+	if w.syncErr == nil {
+		return w.walFile.Sync()
+	}
+	return w.syncErr
+}
+
+func TestDurableMapString_writeError(t *testing.T) {
+	errFoo := errors.New("foo")
+	path := filepath.Join(t.TempDir(), "names.json")
+	m := openTestDurableMapString(t, path, DurableMapStringOption{Sync: WALSyncNone})
+	if err := m.Set("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	wal := &failingWAL{walFile: m.wal, writeErr: errFoo}
+	m.wal = wal
+	if err := m.Set("hello", "world"); !errors.Is(err, errFoo) {
+		t.Fatalf("DurableMapString.Set() = %v, wanted %v", err, errFoo)
+	}
+	if m.Has("hello") {
+		t.Fatal(`DurableMapString.Has("hello") = true, wanted false`)
+	}
+	// the partial record must not break the following records.
+	wal.writeErr = nil
+	if err := m.Set("zoo", "zoo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m = openTestDurableMapString(t, path, DurableMapStringOption{})
+	defer m.Close()
+	a := map[string]string{}
+	m.CopyData(a)
+	exp := map[string]string{"foo": "bar", "zoo": "zoo"}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("DurableMapString = %v, wanted %v", a, exp)
+	}
+}
+
+func TestDurableMapString_syncError(t *testing.T) {
+	errFoo := errors.New("foo")
+	path := filepath.Join(t.TempDir(), "names.json")
+	m := openTestDurableMapString(t, path, DurableMapStringOption{Sync: WALSyncAlways})
+	if err := m.Set("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	m.wal = &failingWAL{walFile: m.wal, syncErr: errFoo}
+	if err := m.Set("hello", "world"); !errors.Is(err, errFoo) {
+		t.Fatalf("DurableMapString.Set() = %v, wanted %v", err, errFoo)
+	}
+	if m.Has("hello") {
+		t.Fatal(`DurableMapString.Has("hello") = true, wanted false`)
+	}
+	// the following changes fail after fsync fails.
+	if err := m.Set("zoo", "zoo"); !errors.Is(err, errFoo) {
+		t.Fatalf("DurableMapString.Set() = %v, wanted %v", err, errFoo)
+	}
+	if err := m.Close(); !errors.Is(err, errFoo) {
+		t.Fatalf("DurableMapString.Close() = %v, wanted %v", err, errFoo)
+	}
+	m = openTestDurableMapString(t, path, DurableMapStringOption{})
+	defer m.Close()
+	a := map[string]string{}
+	m.CopyData(a)
+	exp := map[string]string{"foo": "bar"}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("DurableMapString = %v, wanted %v", a, exp)
+	}
+}
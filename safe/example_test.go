@@ -160,3 +160,61 @@ func ExampleMapString() {
 	// bar: bar world
 	// zoo: zoo world
 }
+
+func ExampleValue() {
+	type config struct {
+		Debug bool
+		Port  int
+	}
+	cfg := &safe.Value[config]{}
+	cfg.Set(config{Port: 80})
+
+	data := map[string]int{
+		"foo": 1,
+		"zoo": 2,
+		"bar": 3,
+	}
+
+	var wg sync.WaitGroup
+	for _, v := range data {
+		v := v
+		wg.Add(1)
+		go func() {
+			// cfg is updated by multiple go routines in parallel.
+			defer wg.Done()
+			// Value.SetFunc is thread safe.
+			cfg.SetFunc(func(c config) config {
+				c.Port += v
+				return c
+			})
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("port: %d\n", cfg.Get().Port)
+	// Output:
+	// port: 86
+}
+
+func ExampleMap() {
+	counts := safe.NewMap(map[string]int{})
+
+	words := []string{"foo", "bar", "foo", "zoo", "foo"}
+
+	var wg sync.WaitGroup
+	for _, w := range words {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// counts is updated by multiple go routine in parallel.
+			// Map.SetFunc is thread safe.
+			counts.SetFunc(w, func(v int, ok bool) int {
+				return v + 1
+			})
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("foo: %d\n", counts.Get("foo"))
+	// Output:
+	// foo: 3
+}
@@ -1,124 +1,330 @@
 package safe
 
 import (
+	"context"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"strconv"
-	"sync"
+	"sync/atomic"
 )
 
 // Int wraps a int.
-// Int must be used as the pointer because Int has sync.RWMutex as a private field.
-// A RWMutex must not be copied after first use.
-// https://golang.org/pkg/sync/#RWMutex
+// Int is lock-free, all operations are done with sync/atomic.
+// So Int doesn't implement Locker and can't be locked by LockAll and Atomically.
+// To change a number together with other containers, use Value[int] instead.
+// Int must be used as the pointer because Int must not be copied after first use.
+// https://golang.org/pkg/sync/atomic/#Int64
 type Int struct {
-	value int
-	mutex sync.RWMutex
+	value    atomic.Int64
+	watchers watchers[int]
+	waiters  waiters[int]
 }
 
 func (i *Int) String() string {
-	i.mutex.RLock()
-	v := i.value
-	i.mutex.RUnlock()
-	return "Int{" + strconv.Itoa(v) + "}"
+	return "Int{" + strconv.Itoa(i.Get()) + "}"
 }
 
 func (i *Int) MarshalJSON() ([]byte, error) {
-	i.mutex.RLock()
-	v := i.value
-	i.mutex.RUnlock()
-	return json.Marshal(v)
+	return json.Marshal(i.Get())
 }
 
 func (i *Int) UnmarshalJSON(b []byte) error {
-	i.mutex.Lock()
-	err := json.Unmarshal(b, &i.value)
-	i.mutex.Unlock()
-	return err
+	var v int
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	i.Set(v)
+	return nil
+}
+
+// MarshalText encodes the value as a decimal number.
+func (i *Int) MarshalText() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(i.Get()), 10), nil
+}
+
+// UnmarshalText decodes the text encoded by MarshalText and sets the value.
+func (i *Int) UnmarshalText(b []byte) error {
+	v, err := strconv.Atoi(string(b))
+	if err != nil {
+		return err
+	}
+	i.Set(v)
+	return nil
+}
+
+// MarshalBinary encodes the value as a varint of encoding/binary.
+func (i *Int) MarshalBinary() ([]byte, error) {
+	return binary.AppendVarint(nil, int64(i.Get())), nil
 }
 
-// Get gets a value with lock.
+// UnmarshalBinary decodes the data encoded by MarshalBinary and sets the value.
+func (i *Int) UnmarshalBinary(b []byte) error {
+	v, n := binary.Varint(b)
+	if n != len(b) || int64(int(v)) != v {
+		return fmt.Errorf("safe: invalid binary data of Int: %v", b)
+	}
+	i.Set(int(v))
+	return nil
+}
+
+// GobEncode encodes the value in the same format as MarshalBinary.
+func (i *Int) GobEncode() ([]byte, error) {
+	return i.MarshalBinary()
+}
+
+// GobDecode decodes the data encoded by GobEncode and sets the value.
+func (i *Int) GobDecode(b []byte) error {
+	return i.UnmarshalBinary(b)
+}
+
+// Get gets a value atomically.
 func (i *Int) Get() int {
-	i.mutex.RLock()
-	v := i.value
-	i.mutex.RUnlock()
-	return v
+	return int(i.value.Load())
 }
 
-// Set sets a value with lock.
+// Set sets a value atomically.
 func (i *Int) Set(v int) {
-	i.mutex.Lock()
-	i.value = v
-	i.mutex.Unlock()
+	i.value.Store(int64(v))
+	i.notify(v)
 }
 
-// SetFunc gets a value and calls the function and sets the returned value with lock.
+// SetFunc gets a value and calls the function and sets the returned value atomically.
 // This is used to update the value based on the original value atomicaly.
+// SetFunc retries with compare-and-swap until no other goroutine changes the value in the meantime,
+// so the function may be called more than once and must not have side effects.
 func (i *Int) SetFunc(f func(v int) int) {
-	i.mutex.Lock()
-	i.value = f(i.value)
-	i.mutex.Unlock()
+	i.update(f)
+}
+
+// SetFuncE is like SetFunc but the value isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the value isn't updated and the panic is returned as *PanicError.
+func (i *Int) SetFuncE(f func(v int) (int, error)) error {
+	f = recoverFunc(f)
+	a, err := i.updateE(f)
+	if err != nil {
+		return err
+	}
+	i.notify(a)
+	return nil
 }
 
-// Add adds a value with lock.
+// Add adds a value atomically.
 func (i *Int) Add(v int) {
-	i.mutex.Lock()
-	i.value += v
-	i.mutex.Unlock()
+	i.AddR(v)
 }
 
-// AddR adds a value with lock.
+// AddR adds a value atomically and returns the new value.
 func (i *Int) AddR(v int) int {
-	i.mutex.Lock()
-	a := i.value + v
-	i.value = a
-	i.mutex.Unlock()
+	a := int(i.value.Add(int64(v)))
+	i.notify(a)
 	return a
 }
 
-// Sub substitutes a value with lock.
+// Sub substitutes a value atomically.
 func (i *Int) Sub(v int) {
-	i.mutex.Lock()
-	i.value -= v
-	i.mutex.Unlock()
+	i.SubR(v)
 }
 
-// SubR substitutes a value with lock.
+// SubR substitutes a value atomically and returns the new value.
 func (i *Int) SubR(v int) int {
-	i.mutex.Lock()
-	a := i.value - v
-	i.value = a
-	i.mutex.Unlock()
+	a := int(i.value.Add(-int64(v)))
+	i.notify(a)
 	return a
 }
 
-// Mul multiplies a value with lock.
+// Mul multiplies a value atomically.
 func (i *Int) Mul(v int) {
-	i.mutex.Lock()
-	i.value *= v
-	i.mutex.Unlock()
+	i.update(func(a int) int {
+		return a * v
+	})
 }
 
-// MulR multiplies a value with lock.
+// MulR multiplies a value atomically and returns the new value.
 func (i *Int) MulR(v int) int {
-	i.mutex.Lock()
-	a := i.value * v
-	i.value = a
-	i.mutex.Unlock()
-	return a
+	return i.update(func(a int) int {
+		return a * v
+	})
 }
 
-// Div divides a value with lock.
+// Div divides a value atomically.
 func (i *Int) Div(v int) {
-	i.mutex.Lock()
-	i.value /= v
-	i.mutex.Unlock()
+	i.update(func(a int) int {
+		return a / v
+	})
 }
 
-// DivR divides a value with lock.
+// DivR divides a value atomically and returns the new value.
 func (i *Int) DivR(v int) int {
-	i.mutex.Lock()
-	a := i.value / v
-	i.value = a
-	i.mutex.Unlock()
+	return i.update(func(a int) int {
+		return a / v
+	})
+}
+
+// update sets f(old) with a compare-and-swap retry loop and returns the new value.
+func (i *Int) update(f func(v int) int) int {
+	a := i.updateUnsafe(f)
+	i.notify(a)
 	return a
 }
+
+// updateE is like updateUnsafe but stops the retry loop when f returns an error.
+func (i *Int) updateE(f func(v int) (int, error)) (int, error) {
+	for {
+		old := i.value.Load()
+		a, err := f(int(old))
+		if err != nil {
+			return 0, err
+		}
+		if i.value.CompareAndSwap(old, int64(a)) {
+			return a, nil
+		}
+	}
+}
+
+// updateUnsafe sets f(old) with a compare-and-swap retry loop and returns the new value.
+func (i *Int) updateUnsafe(f func(v int) int) int {
+	for {
+		old := i.value.Load()
+		a := f(int(old))
+		if i.value.CompareAndSwap(old, int64(a)) {
+			return a
+		}
+	}
+}
+
+// Swap sets a new value atomically and returns the old value.
+func (i *Int) Swap(v int) int {
+	a := int(i.value.Swap(int64(v)))
+	i.notify(v)
+	return a
+}
+
+// CompareAndSwap sets a new value only if the current value is equal to old atomically.
+// true is returned if the value is updated.
+func (i *Int) CompareAndSwap(old, v int) bool {
+	ok := i.value.CompareAndSwap(int64(old), int64(v))
+	if !ok {
+		return false
+	}
+	i.notify(v)
+	return true
+}
+
+// notify wakes the waiters with the written value and sends the latest value to the subscribers.
+func (i *Int) notify(v int) {
+	i.waiters.wake(v)
+	i.watchers.notifyFunc(i.Get)
+}
+
+// WaitUntil blocks until the value satisfies the function or the context is done, and returns the value.
+// The function is called with the current value first and then with every value written by Set, SetFunc, Add, Sub and the other methods,
+// so a value which is overwritten immediately isn't missed.
+// The function is called in the goroutine calling WaitUntil, so a slow function doesn't block writers
+// and a panic in the function is propagated to the caller of WaitUntil.
+// The error of the context is returned if the context is done before the value satisfies the function.
+// Changes by the Unsafe methods don't wake WaitUntil.
+func (i *Int) WaitUntil(ctx context.Context, f func(v int) bool) (int, error) {
+	return i.waiters.wait(ctx, i.Get, f)
+}
+
+// Watch returns a channel which receives the new value after every change.
+// The value sent is loaded when the notification is sent, so a subscriber always gets the latest value eventually.
+// Watch uses WatchCoalesce, so a slow subscriber doesn't stall writers and only the latest value is kept.
+// The channel is closed when the context is done.
+// Changes by the Unsafe methods aren't notified.
+func (i *Int) Watch(ctx context.Context) <-chan int {
+	return i.WatchWithPolicy(ctx, WatchCoalesce, 1)
+}
+
+// WatchWithPolicy is like Watch but the policy for a slow subscriber and the buffer size of the channel can be specified.
+func (i *Int) WatchWithPolicy(ctx context.Context, policy WatchPolicy, buffer int) <-chan int {
+	return i.watchers.add(ctx, policy, buffer)
+}
+
+// IntReadLocked is a handle of Int which is passed to the function of Int.RLock.
+// IntReadLocked must not be used after the function returns.
+type IntReadLocked struct {
+	value int
+}
+
+// IntLocked is a handle of Int which is passed to the function of Int.Lock.
+// IntLocked must not be used after the function returns.
+type IntLocked struct {
+	IntReadLocked
+	changed bool
+}
+
+// Lock calls the function with a handle to read and write the value together without the Unsafe methods.
+// The handle works on a copy of the value, and the last value of the handle is set with compare-and-swap after the function returns.
+// Like SetFunc, if another goroutine changes the value in the meantime, the function is called again with the new value,
+// so the function may be called more than once and must not have side effects.
+// If the handle changes the value, the last value is notified to Watch and WaitUntil.
+func (i *Int) Lock(f func(v *IntLocked)) {
+	for {
+		old := i.value.Load()
+		v := &IntLocked{IntReadLocked: IntReadLocked{value: int(old)}}
+		f(v)
+		if !v.changed {
+			return
+		}
+		if i.value.CompareAndSwap(old, int64(v.value)) {
+			i.notify(v.value)
+			return
+		}
+	}
+}
+
+// RLock calls the function with a read-only handle of the value.
+// The handle works on a copy of the value, so RLock doesn't block the other goroutines.
+func (i *Int) RLock(f func(v *IntReadLocked)) {
+	f(&IntReadLocked{value: i.Get()})
+}
+
+// Get gets a value.
+func (v *IntReadLocked) Get() int {
+	return v.value
+}
+
+// Set sets a value.
+func (v *IntLocked) Set(a int) {
+	v.value = a
+	v.changed = true
+}
+
+// Add adds a value.
+func (v *IntLocked) Add(a int) {
+	v.value += a
+	v.changed = true
+}
+
+// Sub substitutes a value.
+func (v *IntLocked) Sub(a int) {
+	v.value -= a
+	v.changed = true
+}
+
+// Mul multiplies a value.
+func (v *IntLocked) Mul(a int) {
+	v.value *= a
+	v.changed = true
+}
+
+// Div divides a value.
+func (v *IntLocked) Div(a int) {
+	v.value /= a
+	v.changed = true
+}
+
+// SaveFile encodes the Int by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (i *Int) SaveFile(path string) error {
+	return saveFile(path, i)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (i *Int) LoadFile(path string) error {
+	return loadFile(path, i)
+}
@@ -1,11 +1,42 @@
 package safe
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
 	"encoding/json"
+	"errors"
+	"math"
 	"sync"
 	"testing"
+	"time"
 )
 
+func newTestInt(v int) *Int {
+	i := &Int{}
+	i.Set(v)
+	return i
+}
+
+// mutexInt is the former implementation of Int, which is used only to compare the performance.
+type mutexInt struct {
+	value int
+	mutex sync.RWMutex
+}
+
+func (i *mutexInt) Get() int {
+	i.mutex.RLock()
+	v := i.value
+	i.mutex.RUnlock()
+	return v
+}
+
+func (i *mutexInt) Add(v int) {
+	i.mutex.Lock()
+	i.value += v
+	i.mutex.Unlock()
+}
+
 func TestInt_String(t *testing.T) {
 	age := &Int{}
 	var wg sync.WaitGroup
@@ -80,14 +111,63 @@ func TestInt_UnmarshalJSON(t *testing.T) {
 	}
 	exp := 10
 
-	if age.value != exp {
-		t.Fatalf("Int.UnmarshalJSON() = %d, wanted %d", age.value, exp)
+	if age.Get() != exp {
+		t.Fatalf("Int.UnmarshalJSON() = %d, wanted %d", age.Get(), exp)
+	}
+}
+
+func TestInt_encoding(t *testing.T) {
+	age := newTestInt(-10)
+	text, err := age.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "-10" {
+		t.Fatalf("Int.MarshalText() = %s, wanted -10", text)
+	}
+	data := []struct {
+		title   string
+		marshal func(*Int) ([]byte, error)
+		decode  func(*Int, []byte) error
+	}{
+		{"text", (*Int).MarshalText, (*Int).UnmarshalText},
+		{"binary", (*Int).MarshalBinary, (*Int).UnmarshalBinary},
+		{"gob", func(i *Int) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(i)
+			return buf.Bytes(), err
+		}, func(i *Int, buf []byte) error {
+			return gob.NewDecoder(bytes.NewReader(buf)).Decode(i)
+		}},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			for _, exp := range []int{0, 1, -10, math.MaxInt, math.MinInt} {
+				buf, err := d.marshal(newTestInt(exp))
+				if err != nil {
+					t.Fatal(err)
+				}
+				a := newTestInt(5)
+				if err := d.decode(a, buf); err != nil {
+					t.Fatal(err)
+				}
+				if a.Get() != exp {
+					t.Fatalf("Int.Get() = %d, wanted %d", a.Get(), exp)
+				}
+			}
+		})
+	}
+	if err := age.UnmarshalBinary([]byte{0x80}); err == nil {
+		t.Fatal("Int.UnmarshalBinary() must fail with invalid data")
+	}
+	if a := age.Get(); a != -10 {
+		t.Fatalf("Int.Get() = %d, wanted %d", a, -10)
 	}
 }
 
 func TestInt_Get(t *testing.T) {
 	v := 5
-	age := &Int{value: v}
+	age := newTestInt(v)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	a := 0
@@ -110,7 +190,7 @@ func TestInt_Get(t *testing.T) {
 }
 
 func TestInt_Set(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -129,7 +209,7 @@ func TestInt_Set(t *testing.T) {
 }
 
 func TestInt_SetFunc(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -151,8 +231,36 @@ func TestInt_SetFunc(t *testing.T) {
 	}
 }
 
+func TestInt_SetFuncE(t *testing.T) {
+	age := newTestInt(1)
+	errNegative := errors.New("negative")
+	decrement := func(v int) (int, error) {
+		if v == 0 {
+			return 0, errNegative
+		}
+		return v - 1, nil
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	errs := make([]error, 2)
+	for i := 0; i < 2; i++ {
+		i := i
+		go func() {
+			errs[i] = age.SetFuncE(decrement)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("Int.SetFuncE() = %v, %v, wanted one success and one error", errs[0], errs[1])
+	}
+	if a := age.Get(); a != 0 {
+		t.Fatalf("Int.Get() = %d, wanted %d", a, 0)
+	}
+}
+
 func TestInt_Add(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -171,7 +279,7 @@ func TestInt_Add(t *testing.T) {
 }
 
 func TestInt_AddR(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -190,7 +298,7 @@ func TestInt_AddR(t *testing.T) {
 }
 
 func TestInt_Sub(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -209,7 +317,7 @@ func TestInt_Sub(t *testing.T) {
 }
 
 func TestInt_SubR(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -228,7 +336,7 @@ func TestInt_SubR(t *testing.T) {
 }
 
 func TestInt_Mul(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -247,7 +355,7 @@ func TestInt_Mul(t *testing.T) {
 }
 
 func TestInt_MulR(t *testing.T) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -266,7 +374,7 @@ func TestInt_MulR(t *testing.T) {
 }
 
 func TestInt_Div(t *testing.T) {
-	age := &Int{value: 20}
+	age := newTestInt(20)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -285,7 +393,7 @@ func TestInt_Div(t *testing.T) {
 }
 
 func TestInt_DivR(t *testing.T) {
-	age := &Int{value: 20}
+	age := newTestInt(20)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -304,7 +412,7 @@ func TestInt_DivR(t *testing.T) {
 }
 
 func BenchmarkInt_Add(b *testing.B) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		age.Add(1)
@@ -312,9 +420,290 @@ func BenchmarkInt_Add(b *testing.B) {
 }
 
 func BenchmarkInt_AddR(b *testing.B) {
-	age := &Int{value: 5}
+	age := newTestInt(5)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		age.AddR(1)
 	}
 }
+
+func BenchmarkInt_Add_parallel(b *testing.B) {
+	b.Run("atomic", func(b *testing.B) {
+		age := &Int{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				age.Add(1)
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		age := &mutexInt{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				age.Add(1)
+			}
+		})
+	})
+}
+
+func BenchmarkInt_Get_parallel(b *testing.B) {
+	b.Run("atomic", func(b *testing.B) {
+		age := &Int{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				age.Get()
+			}
+		})
+	})
+	b.Run("mutex", func(b *testing.B) {
+		age := &mutexInt{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				age.Get()
+			}
+		})
+	})
+}
+
+func TestInt_Swap(t *testing.T) {
+	age := newTestInt(5)
+	if a := age.Swap(10); a != 5 {
+		t.Fatalf("Int.Swap() = %d, wanted 5", a)
+	}
+	if a := age.Get(); a != 10 {
+		t.Fatalf("Int.Get() = %d, wanted 10", a)
+	}
+}
+
+func TestInt_CompareAndSwap(t *testing.T) {
+	age := &Int{}
+	var wg sync.WaitGroup
+	var swapped Int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			if age.CompareAndSwap(0, 1) {
+				swapped.Add(1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := swapped.Get(); a != 1 {
+		t.Fatalf("the number of successful CompareAndSwap = %d, wanted 1", a)
+	}
+	if a := age.Get(); a != 1 {
+		t.Fatalf("Int.Get() = %d, wanted 1", a)
+	}
+}
+
+func TestInt_Watch(t *testing.T) {
+	age := &Int{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := age.Watch(ctx)
+	age.Set(5)
+	if a := receiveWithTimeout(t, ch); a != 5 {
+		t.Fatalf("received %d, wanted %d", a, 5)
+	}
+	age.Add(2)
+	if a := receiveWithTimeout(t, ch); a != 7 {
+		t.Fatalf("received %d, wanted %d", a, 7)
+	}
+	age.SetFunc(func(v int) int {
+		return v * 2
+	})
+	if a := receiveWithTimeout(t, ch); a != 14 {
+		t.Fatalf("received %d, wanted %d", a, 14)
+	}
+	if err := json.Unmarshal([]byte("3"), age); err != nil {
+		t.Fatal(err)
+	}
+	if a := receiveWithTimeout(t, ch); a != 3 {
+		t.Fatalf("received %d, wanted %d", a, 3)
+	}
+	age.CompareAndSwap(0, 1)
+	select {
+	case a := <-ch:
+		t.Fatalf("received %d, but failed CompareAndSwap must not be notified", a)
+	default:
+	}
+}
+
+func TestInt_Watch_latest(t *testing.T) {
+	age := &Int{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := age.Watch(ctx)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			age.Add(1)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	// the subscriber eventually gets the latest value.
+	for {
+		if a := receiveWithTimeout(t, ch); a == 100 {
+			break
+		}
+	}
+}
+
+func TestInt_WaitUntil(t *testing.T) {
+	age := newTestInt(3)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if a, err := age.WaitUntil(ctx, func(v int) bool { return v == 3 }); err != nil || a != 3 {
+		t.Fatalf("Int.WaitUntil() = %d, %v, wanted %d, nil", a, err, 3)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if a, err := age.WaitUntil(ctx, func(v int) bool { return v == 0 }); err != nil || a != 0 {
+			t.Errorf("Int.WaitUntil() = %d, %v, wanted %d, nil", a, err, 0)
+		}
+		wg.Done()
+	}()
+	for i := 0; i < 3; i++ {
+		age.Sub(1)
+	}
+	wg.Wait()
+}
+
+func TestInt_WaitUntil_transient(t *testing.T) {
+	age := newTestInt(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		if _, err := age.WaitUntil(ctx, func(v int) bool { return v == 0 }); err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+	for age.waiters.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the value 0 is overwritten immediately but the waiter must be woken.
+	age.Sub(1)
+	age.Add(1)
+	<-done
+}
+
+func TestInt_WaitUntil_canceled(t *testing.T) {
+	age := newTestInt(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := age.WaitUntil(ctx, func(v int) bool { return v == 0 }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Int.WaitUntil() = %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if a := age.waiters.n.Load(); a != 0 {
+		t.Fatalf("the number of waiters = %d, wanted %d", a, 0)
+	}
+}
+
+func TestInt_WaitUntil_panic(t *testing.T) {
+	age := newTestInt(1)
+	done := make(chan any)
+	go func() {
+		defer func() {
+			done <- recover()
+		}()
+		_, _ = age.WaitUntil(context.Background(), func(v int) bool {
+			if v == 0 {
+				panic("cond")
+			}
+			return false
+		})
+	}()
+	for age.waiters.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the panic of the function must not be propagated to the writer.
+	age.Sub(1)
+	if a := <-done; a != "cond" {
+		t.Fatalf("Int.WaitUntil() panicked with %v, wanted %v", a, "cond")
+	}
+	age.Sub(1)
+	if a := age.Get(); a != -1 {
+		t.Fatalf("Int.Get() = %d, wanted %d", a, -1)
+	}
+}
+
+func TestInt_Lock(t *testing.T) {
+	age := newTestInt(1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		for i := 0; i < 100; i++ {
+			age.Add(1)
+		}
+		wg.Done()
+	}()
+	go func() {
+		for i := 0; i < 100; i++ {
+			age.Lock(func(v *IntLocked) {
+				// the read-modify-write isn't interleaved with Add.
+				v.Set(v.Get() + 2)
+				v.Sub(1)
+				v.Mul(3)
+				v.Div(3)
+			})
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Get(); a != 201 {
+		t.Fatalf("Int.Get() = %d, wanted %d", a, 201)
+	}
+}
+
+func TestInt_Lock_notify(t *testing.T) {
+	age := newTestInt(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch := age.Watch(ctx)
+	done := make(chan int)
+	go func() {
+		v, err := age.WaitUntil(ctx, func(v int) bool { return v == 0 })
+		if err != nil {
+			t.Error(err)
+		}
+		done <- v
+	}()
+	for age.waiters.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	age.Lock(func(v *IntLocked) {
+		v.Sub(1)
+	})
+	if a := <-done; a != 0 {
+		t.Fatalf("Int.WaitUntil() = %d, wanted %d", a, 0)
+	}
+	if a := receiveWithTimeout(t, ch); a != 0 {
+		t.Fatalf("received %d, wanted %d", a, 0)
+	}
+}
+
+func TestInt_RLock(t *testing.T) {
+	age := newTestInt(1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Add(1)
+		wg.Done()
+	}()
+	go func() {
+		age.RLock(func(v *IntReadLocked) {
+			a := v.Get()
+			if b := v.Get(); a != b {
+				t.Errorf("IntReadLocked.Get() = %d, wanted %d", b, a)
+			}
+		})
+		wg.Done()
+	}()
+	wg.Wait()
+}
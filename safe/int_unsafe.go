@@ -1,31 +1,38 @@
 package safe
 
+// Int is lock-free, so the Unsafe methods of Int are still atomic individually.
+// They don't notify Watch and WaitUntil.
+
 // GetUnsafe gets a value without lock.
 func (i *Int) GetUnsafe() int {
-	return i.value
+	return int(i.value.Load())
 }
 
 // SetUnsafe sets a value without lock.
 func (i *Int) SetUnsafe(v int) {
-	i.value = v
+	i.value.Store(int64(v))
 }
 
 // AddUnsafe adds a value without lock.
 func (i *Int) AddUnsafe(v int) {
-	i.value += v
+	i.value.Add(int64(v))
 }
 
 // SubUnsafe substitutes a value without lock.
 func (i *Int) SubUnsafe(v int) {
-	i.value -= v
+	i.value.Add(-int64(v))
 }
 
 // MulUnsafe multiplies a value without lock.
 func (i *Int) MulUnsafe(v int) {
-	i.value *= v
+	i.updateUnsafe(func(a int) int {
+		return a * v
+	})
 }
 
 // DivUnsafe divides a value without lock.
 func (i *Int) DivUnsafe(v int) {
-	i.value /= v
+	i.updateUnsafe(func(a int) int {
+		return a / v
+	})
 }
@@ -0,0 +1,86 @@
+package safe
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// Locker is a container which can be locked by LockAll and Atomically.
+// String, Value, Map, MapString, Slice and Set implement Locker,
+// because they have the Unsafe methods to read and write them while they are locked.
+// Bool and Int are lock-free and don't implement Locker, use Value[bool] and Value[int] instead.
+type Locker interface {
+	mutexes() []*rwMutex
+}
+
+// waitRechecker is a container whose waiters check the current value again when LockAll unlocks it,
+// because the changes by the Unsafe methods don't wake the waiters.
+type waitRechecker interface {
+	recheckWaiters()
+}
+
+// LockAll locks all containers and returns the function to unlock them.
+// The locks are taken in the order of their addresses,
+// so two goroutines which lock the same containers in different orders can't deadlock.
+// A container which is passed more than once is locked only once.
+//
+// While the containers are locked, they must be read and written only by the Unsafe methods.
+// The other methods of the locked containers block until the function to unlock is called.
+// The changes by the Unsafe methods aren't notified to Watch.
+// Instead, the function to unlock makes the waiters of Map.WaitForKey and Map.WaitForChange
+// check the current values, so they are woken by the changes made while the containers are locked.
+func LockAll(containers ...Locker) func() {
+	var mutexes []*rwMutex
+	for _, c := range containers {
+		mutexes = append(mutexes, c.mutexes()...)
+	}
+	sort.Slice(mutexes, func(i, j int) bool {
+		return uintptr(unsafe.Pointer(mutexes[i])) < uintptr(unsafe.Pointer(mutexes[j]))
+	})
+	locked := mutexes[:0]
+	for _, mutex := range mutexes {
+		if len(locked) != 0 && locked[len(locked)-1] == mutex {
+			continue
+		}
+		mutex.Lock()
+		locked = append(locked, mutex)
+	}
+	return func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			locked[i].Unlock()
+		}
+		for _, c := range containers {
+			if r, ok := c.(waitRechecker); ok {
+				r.recheckWaiters()
+			}
+		}
+	}
+}
+
+// Atomically calls the function while all containers are locked by LockAll.
+// The containers are unlocked even if the function panics.
+func Atomically(f func(), containers ...Locker) {
+	unlock := LockAll(containers...)
+	defer unlock()
+	f()
+}
+
+// rlockPair read-locks both a and b and returns the function to unlock them.
+// The mutexes are always locked in the order of their addresses,
+// so two goroutines which lock the same pair in opposite orders can't deadlock.
+// If a and b are the same mutex, it is locked only once.
+func rlockPair(a, b *rwMutex) func() {
+	if a == b {
+		a.RLock()
+		return a.RUnlock
+	}
+	if uintptr(unsafe.Pointer(a)) > uintptr(unsafe.Pointer(b)) {
+		a, b = b, a
+	}
+	a.RLock()
+	b.RLock()
+	return func() {
+		b.RUnlock()
+		a.RUnlock()
+	}
+}
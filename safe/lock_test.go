@@ -0,0 +1,124 @@
+package safe
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockAll(t *testing.T) {
+	count := &Value[int]{}
+	jobs := NewMapString(map[string]string{})
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			unlock := LockAll(count, jobs)
+			count.SetUnsafe(count.GetUnsafe() + 1)
+			jobs.SetUnsafe("count", strconv.Itoa(count.GetUnsafe()))
+			unlock()
+			wg.Done()
+		}()
+		go func() {
+			// the opposite order must not deadlock.
+			unlock := LockAll(jobs, count, jobs)
+			count.SetUnsafe(count.GetUnsafe() + 1)
+			jobs.SetUnsafe("count", strconv.Itoa(count.GetUnsafe()))
+			unlock()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := count.Get(); a != 200 {
+		t.Fatalf("Value.Get() = %d, wanted %d", a, 200)
+	}
+	if a := jobs.Get("count"); a != "200" {
+		t.Fatalf(`MapString.Get("count") = %s, wanted 200`, a)
+	}
+}
+
+func TestLockAll_exclude(t *testing.T) {
+	count := &Value[int]{}
+	flag := &Value[bool]{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		for i := 0; i < 100; i++ {
+			count.SetFunc(func(v int) int { return v + 1 })
+			flag.SetFunc(func(v bool) bool { return !v })
+		}
+		wg.Done()
+	}()
+	go func() {
+		for i := 0; i < 100; i++ {
+			Atomically(func() {
+				// the read-modify-write isn't interleaved with SetFunc.
+				count.SetUnsafe(count.GetUnsafe() + 1)
+				flag.SetUnsafe(!flag.GetUnsafe())
+			}, count, flag)
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := count.Get(); a != 200 {
+		t.Fatalf("Value.Get() = %d, wanted %d", a, 200)
+	}
+	if a := flag.Get(); a {
+		t.Fatalf("Value.Get() = %t, wanted %t", a, false)
+	}
+}
+
+func TestAtomically_panic(t *testing.T) {
+	name := &String{}
+	names := NewMapString(map[string]string{})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("the panic must be propagated")
+			}
+		}()
+		Atomically(func() {
+			panic("atomically")
+		}, name, names)
+	}()
+	// the locks must be released.
+	name.Set("foo")
+	names.Set("foo", "bar")
+}
+
+func TestLockAll_containers(t *testing.T) {
+	containers := []Locker{
+		&String{}, &Value[int]{}, NewMap(map[string]int{}), NewMapString(nil),
+		NewSlice[int](nil), NewSet[int](),
+	}
+	unlock := LockAll(containers...)
+	unlock()
+	unlock = LockAll(containers...)
+	unlock()
+}
+
+func TestAtomically_wake(t *testing.T) {
+	name := &String{}
+	jobs := NewMapString(map[string]string{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if v, err := jobs.WaitForKey(ctx, "result"); err != nil || v != "done" {
+			t.Errorf(`MapString.WaitForKey("result") = %s, %v, wanted done, nil`, v, err)
+		}
+		wg.Done()
+	}()
+	for jobs.waiters.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the changes by the Unsafe methods wake the waiters when the containers are unlocked.
+	Atomically(func() {
+		name.SetUnsafe("foo")
+		jobs.SetUnsafe("result", "done")
+	}, name, jobs)
+	wg.Wait()
+}
@@ -0,0 +1,490 @@
+package safe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Map wraps map[K]V.
+// Map must be created by NewMap.
+type Map[K comparable, V any] struct {
+	value    map[K]V
+	mutex    rwMutex
+	watchers watchers[MapEvent[K, V]]
+	waiters  waiters[MapEvent[K, V]]
+}
+
+// MapEvent is a change of a key, which is sent to the channel returned by Map.Watch.
+type MapEvent[K comparable, V any] struct {
+	Key K
+	// Old is the value before the change. If Loaded is false, Old is the zero value.
+	Old V
+	// New is the value after the change. If Deleted is true, New is the zero value.
+	New V
+	// Loaded is true if the map had the key before the change.
+	Loaded bool
+	// Deleted is true if the key is deleted.
+	Deleted bool
+}
+
+// ComputeOp is the operation returned by the function of Compute.
+type ComputeOp int
+
+const (
+	// ComputeKeep keeps the map as it is.
+	ComputeKeep ComputeOp = iota
+	// ComputeStore sets the returned value to the key.
+	ComputeStore
+	// ComputeDelete deletes the key.
+	ComputeDelete
+)
+
+// NewMap creates a Map.
+// The argument `value` must not be nil.
+// Note that the argument `value` is holden in Map, so don't read and write `value` out of the Map.
+func NewMap[K comparable, V any](value map[K]V) *Map[K, V] {
+	// To avoid the heap allocation, don't copy `value` and create a new map.
+	return &Map[K, V]{ // escapes to heap
+		value: value,
+	}
+}
+
+func (m *Map[K, V]) String() string {
+	m.mutex.RLock()
+	v := "Map{" + fmt.Sprintf("%v", m.value) + "}"
+	m.mutex.RUnlock()
+	return v
+}
+
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	m.mutex.RLock()
+	b, err := json.Marshal(m.value)
+	m.mutex.RUnlock()
+	return b, err
+}
+
+// UnmarshalJSON decodes a JSON object and sets the pairs of the key and value to the map.
+// The existing keys which aren't in the document are kept.
+func (m *Map[K, V]) UnmarshalJSON(buf []byte) error {
+	var v map[K]V
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	m.setAll(v)
+	return nil
+}
+
+// setAll sets the pairs of the key and value to the map with lock, and notifies the changes.
+func (m *Map[K, V]) setAll(v map[K]V) {
+	m.mutex.Lock()
+	if m.value == nil {
+		m.value = make(map[K]V, len(v))
+	}
+	var events []MapEvent[K, V]
+	if m.watchers.n.Load() != 0 || m.waiters.n.Load() != 0 {
+		events = make([]MapEvent[K, V], 0, len(v))
+	}
+	for k, a := range v {
+		if events != nil {
+			old, ok := m.value[k]
+			events = append(events, MapEvent[K, V]{Key: k, Old: old, New: a, Loaded: ok})
+		}
+		m.value[k] = a
+	}
+	for _, e := range events {
+		m.waiters.wake(e)
+	}
+	m.watchers.notify(m.mutex.Unlock, events...)
+}
+
+// Get gets a value from the map with lock.
+func (m *Map[K, V]) Get(k K) V {
+	m.mutex.RLock()
+	v := m.value[k]
+	m.mutex.RUnlock()
+	return v
+}
+
+// GetOk gets a value from the map with lock.
+func (m *Map[K, V]) GetOk(k K) (V, bool) {
+	m.mutex.RLock()
+	v, ok := m.value[k]
+	m.mutex.RUnlock()
+	return v, ok
+}
+
+// Has checks whether the map has the key with lock.
+func (m *Map[K, V]) Has(k K) bool {
+	m.mutex.RLock()
+	_, ok := m.value[k]
+	m.mutex.RUnlock()
+	return ok
+}
+
+// Len gets the length of the map with lock.
+func (m *Map[K, V]) Len() int {
+	m.mutex.RLock()
+	v := len(m.value)
+	m.mutex.RUnlock()
+	return v
+}
+
+// Delete deletes the key from the map with lock.
+func (m *Map[K, V]) Delete(k K) {
+	m.DeleteROk(k)
+}
+
+// DeleteR deletes the key from the map and returns the value with lock.
+func (m *Map[K, V]) DeleteR(k K) V {
+	v, _ := m.DeleteROk(k)
+	return v
+}
+
+// DeleteROk deletes the key from the map and returns the value with lock.
+func (m *Map[K, V]) DeleteROk(k K) (V, bool) {
+	m.mutex.Lock()
+	v, ok := m.value[k]
+	if !ok {
+		m.mutex.Unlock()
+		return v, false
+	}
+	delete(m.value, k)
+	m.notifyDelete(k, v)
+	return v, true
+}
+
+// Set sets the key and value to the map with lock.
+func (m *Map[K, V]) Set(k K, v V) {
+	m.mutex.Lock()
+	old, ok := m.value[k]
+	m.value[k] = v
+	m.notifySet(k, old, v, ok)
+}
+
+// SetDefault sets the key and value to the map if the map doesn't have the key with lock.
+func (m *Map[K, V]) SetDefault(k K, v V) {
+	m.SetDefaultR(k, v)
+}
+
+// SetDefaultR sets the key and value to the map if the map doesn't have the key and returns the value with lock.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (m *Map[K, V]) SetDefaultR(k K, v V) (V, bool) {
+	m.mutex.Lock()
+	a, ok := m.value[k]
+	if ok {
+		m.mutex.Unlock()
+		return a, true
+	}
+	m.value[k] = v
+	m.notifySet(k, a, v, false)
+	return v, false
+}
+
+// SetFunc gets a value of the key from the map and calls the function and sets the returned value to the map with lock.
+// This is used to update the value based on the original value atomicaly.
+func (m *Map[K, V]) SetFunc(k K, f func(V, bool) V) {
+	m.mutex.Lock()
+	v, ok := m.value[k]
+	a, _ := m.apply(func(v V, ok bool) (V, error) {
+		return f(v, ok), nil
+	}, v, ok)
+	m.value[k] = a
+	m.notifySet(k, v, a, ok)
+}
+
+// SetFuncE is like SetFunc but the map isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the map isn't updated and the panic is returned as *PanicError.
+func (m *Map[K, V]) SetFuncE(k K, f func(V, bool) (V, error)) error {
+	f = recoverMapFunc(f)
+	m.mutex.Lock()
+	v, ok := m.value[k]
+	a, err := m.apply(f, v, ok)
+	if err != nil {
+		m.mutex.Unlock()
+		return err
+	}
+	m.value[k] = a
+	m.notifySet(k, v, a, ok)
+	return nil
+}
+
+// apply calls the function and releases the lock if the function panics.
+func (m *Map[K, V]) apply(f func(V, bool) (V, error), v V, ok bool) (a V, err error) {
+	m.callLocked(func() {
+		a, err = f(v, ok)
+	})
+	return a, err
+}
+
+// callLocked calls the function and releases the lock if the function panics.
+func (m *Map[K, V]) callLocked(f func()) {
+	done := false
+	defer func() {
+		if !done {
+			m.mutex.Unlock()
+		}
+	}()
+	f()
+	done = true
+}
+
+// Compute calls the function with the current value of the key and applies the returned operation with lock.
+// ok is true if the map has the key.
+// Compute returns the value of the key after the operation and whether the map has the key.
+// Unlike SetFunc, the function can keep the map as it is or delete the key.
+func (m *Map[K, V]) Compute(k K, f func(old V, ok bool) (V, ComputeOp)) (V, bool) {
+	m.mutex.Lock()
+	old, ok := m.value[k]
+	var v V
+	var op ComputeOp
+	m.callLocked(func() {
+		v, op = f(old, ok)
+	})
+	switch op {
+	case ComputeStore:
+		m.value[k] = v
+		m.notifySet(k, old, v, ok)
+		return v, true
+	case ComputeDelete:
+		var zero V
+		if !ok {
+			m.mutex.Unlock()
+			return zero, false
+		}
+		delete(m.value, k)
+		m.notifyDelete(k, old)
+		return zero, false
+	default:
+		m.mutex.Unlock()
+		return old, ok
+	}
+}
+
+// ComputeIfAbsent calls the function only if the map doesn't have the key and sets the returned value with lock.
+// If the function returns false, the value isn't set like null in Java's ConcurrentHashMap.computeIfAbsent.
+// ComputeIfAbsent returns the current or computed value and whether the map has the key.
+func (m *Map[K, V]) ComputeIfAbsent(k K, f func() (V, bool)) (V, bool) {
+	return m.Compute(k, func(old V, ok bool) (V, ComputeOp) {
+		if ok {
+			return old, ComputeKeep
+		}
+		v, store := f()
+		if !store {
+			return v, ComputeKeep
+		}
+		return v, ComputeStore
+	})
+}
+
+// ComputeIfPresent calls the function only if the map has the key and sets the returned value with lock.
+// If the function returns false, the key is deleted like null in Java's ConcurrentHashMap.computeIfPresent.
+// ComputeIfPresent returns the computed value and whether the map has the key.
+func (m *Map[K, V]) ComputeIfPresent(k K, f func(old V) (V, bool)) (V, bool) {
+	return m.Compute(k, func(old V, ok bool) (V, ComputeOp) {
+		if !ok {
+			return old, ComputeKeep
+		}
+		v, keep := f(old)
+		if !keep {
+			return v, ComputeDelete
+		}
+		return v, ComputeStore
+	})
+}
+
+// Swap sets the key and value to the map and returns the previous value with lock.
+// true is returned if the map has had the key.
+func (m *Map[K, V]) Swap(k K, v V) (V, bool) {
+	m.mutex.Lock()
+	a, ok := m.value[k]
+	m.value[k] = v
+	m.notifySet(k, a, v, ok)
+	return a, ok
+}
+
+// CompareAndSwap sets the value of the key only if the map has the key and the current value is equal to old with lock.
+// true is returned if the value is updated.
+// Like sync.Map, CompareAndSwap panics if V isn't comparable.
+func (m *Map[K, V]) CompareAndSwap(k K, old, v V) bool {
+	m.mutex.Lock()
+	a, ok := m.value[k]
+	if !ok || !m.equalLocked(a, old) {
+		m.mutex.Unlock()
+		return false
+	}
+	m.value[k] = v
+	m.notifySet(k, a, v, true)
+	return true
+}
+
+// CompareAndDelete deletes the key only if the current value is equal to old with lock.
+// true is returned if the key is deleted.
+// Like sync.Map, CompareAndDelete panics if V isn't comparable.
+func (m *Map[K, V]) CompareAndDelete(k K, old V) bool {
+	m.mutex.Lock()
+	a, ok := m.value[k]
+	if !ok || !m.equalLocked(a, old) {
+		m.mutex.Unlock()
+		return false
+	}
+	delete(m.value, k)
+	m.notifyDelete(k, a)
+	return true
+}
+
+// equalLocked compares two values.
+// If V isn't comparable, equalLocked releases the lock and panics.
+func (m *Map[K, V]) equalLocked(a, b V) bool {
+	defer func() {
+		if err := recover(); err != nil {
+			m.mutex.Unlock()
+			panic(err)
+		}
+	}()
+	return any(a) == any(b)
+}
+
+// Range gets all pairs of the key and value from the map with lock and calls the function.
+func (m *Map[K, V]) Range(f func(k K, v V)) {
+	m.mutex.RLock()
+	copiedM := make(map[K]V, len(m.value))
+	for k, v := range m.value {
+		copiedM[k] = v
+	}
+	m.mutex.RUnlock()
+	for k, v := range copiedM {
+		f(k, v)
+	}
+}
+
+// RangeB gets all pairs of the key and value from the map with lock and calls the function.
+// If the function returns false, the loop ends.
+func (m *Map[K, V]) RangeB(f func(k K, v V) bool) {
+	m.mutex.RLock()
+	copiedM := make(map[K]V, len(m.value))
+	for k, v := range m.value {
+		copiedM[k] = v
+	}
+	m.mutex.RUnlock()
+
+	for k, v := range copiedM {
+		if !f(k, v) {
+			break
+		}
+	}
+}
+
+// Copy copies all pairs of the key and value to target.
+func (m *Map[K, V]) Copy(target *Map[K, V]) {
+	m.mutex.RLock()
+	for k, v := range m.value {
+		target.value[k] = v
+	}
+	m.mutex.RUnlock()
+}
+
+// CopyData copies an internal map[K]V to target.
+func (m *Map[K, V]) CopyData(target map[K]V) {
+	m.mutex.RLock()
+	for k, v := range m.value {
+		target[k] = v
+	}
+	m.mutex.RUnlock()
+}
+
+// WaitForKey blocks until the map has the key or the context is done, and returns the value.
+// If the map already has the key, WaitForKey returns immediately.
+// Set, SetDefault, SetFunc, Swap, CompareAndSwap, UnmarshalJSON, Update and Lock wake WaitForKey.
+// The error of the context is returned if the context is done before the key is set.
+// Changes by Copy don't wake WaitForKey,
+// and changes by the Unsafe methods wake WaitForKey only when Atomically or the function returned by LockAll unlocks the map.
+func (m *Map[K, V]) WaitForKey(ctx context.Context, k K) (V, error) {
+	return m.wait(ctx, k, func(e MapEvent[K, V]) bool {
+		return e.Key == k && !e.Deleted
+	})
+}
+
+// WaitForChange blocks until the map has the key and its value is different from lastSeen
+// or the context is done, and returns the value.
+// If the current value is already different from lastSeen, WaitForChange returns immediately.
+// The deletion of the key isn't a change, so WaitForChange keeps waiting until the key is set again.
+// WaitForChange is woken by the same methods as WaitForKey.
+// Like CompareAndSwap, WaitForChange panics if V isn't comparable.
+func (m *Map[K, V]) WaitForChange(ctx context.Context, k K, lastSeen V) (V, error) {
+	// panic before taking the lock if V isn't comparable.
+	_ = any(lastSeen) == any(lastSeen)
+	return m.wait(ctx, k, func(e MapEvent[K, V]) bool {
+		return e.Key == k && !e.Deleted && any(e.New) != any(lastSeen)
+	})
+}
+
+// wait blocks until an event satisfies cond or the context is done.
+// The current state of the key is checked first as an event whose Deleted is true if the map doesn't have the key.
+func (m *Map[K, V]) wait(ctx context.Context, k K, cond func(MapEvent[K, V]) bool) (V, error) {
+	// writers wake the waiters with the lock,
+	// so the waiter is registered with the read lock not to miss a change.
+	// cond is called by writers to queue only the events of the key.
+	m.mutex.RLock()
+	w := m.waiters.add(func() MapEvent[K, V] {
+		v, ok := m.value[k]
+		return MapEvent[K, V]{Key: k, New: v, Loaded: ok, Deleted: !ok}
+	}, cond)
+	m.mutex.RUnlock()
+	e, err := m.waiters.block(ctx, w, nil)
+	return e.New, err
+}
+
+// Watch returns a channel which receives an event after every change of a key.
+// Events are sent after Set, SetDefault, SetDefaultR, SetFunc, Swap, CompareAndSwap,
+// Delete, DeleteR, DeleteROk, CompareAndDelete, UnmarshalJSON, Update and Lock, in the same order as the changes.
+// Changes by the Unsafe methods and Copy aren't notified.
+// Watch uses WatchCoalesce with a buffer, so a slow subscriber doesn't stall writers but may miss old events.
+// The channel is closed when the context is done.
+func (m *Map[K, V]) Watch(ctx context.Context) <-chan MapEvent[K, V] {
+	return m.WatchWithPolicy(ctx, WatchCoalesce, defaultMapWatchBuffer)
+}
+
+// WatchWithPolicy is like Watch but the policy for a slow subscriber and the buffer size of the channel can be specified.
+func (m *Map[K, V]) WatchWithPolicy(ctx context.Context, policy WatchPolicy, buffer int) <-chan MapEvent[K, V] {
+	return m.watchers.add(ctx, policy, buffer)
+}
+
+// notifySet wakes the waiters, releases the lock and notifies that the key is set.
+// The waiters are woken with the lock, so they are woken in the same order as the changes.
+func (m *Map[K, V]) notifySet(k K, old, v V, loaded bool) {
+	e := MapEvent[K, V]{Key: k, Old: old, New: v, Loaded: loaded}
+	m.waiters.wake(e)
+	m.watchers.notify(m.mutex.Unlock, e)
+}
+
+// notifyDelete releases the lock and notifies that the key is deleted.
+func (m *Map[K, V]) notifyDelete(k K, old V) {
+	m.watchers.notify(m.mutex.Unlock, MapEvent[K, V]{Key: k, Old: old, Loaded: true, Deleted: true})
+}
+
+func (m *Map[K, V]) mutexes() []*rwMutex {
+	return []*rwMutex{&m.mutex}
+}
+
+func (m *Map[K, V]) recheckWaiters() {
+	if m.waiters.n.Load() == 0 {
+		return
+	}
+	m.mutex.RLock()
+	m.waiters.recheck()
+	m.mutex.RUnlock()
+}
+
+// SaveFile encodes the Map by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (m *Map[K, V]) SaveFile(path string) error {
+	return saveFile(path, m)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (m *Map[K, V]) LoadFile(path string) error {
+	return loadFile(path, m)
+}
@@ -0,0 +1,104 @@
+package safe
+
+import (
+	"iter"
+	"sort"
+)
+
+// All returns an iterator over all pairs of the key and value in the map.
+// The iteration copies the map with the read lock when the loop starts and doesn't hold the lock,
+// so the loop body can call any methods of the map.
+// Use AllLocked to iterate without copying the map.
+//
+//	for k, v := range m.All() {
+//		fmt.Println(k, v)
+//	}
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.mutex.RLock()
+		copiedM := make(map[K]V, len(m.value))
+		for k, v := range m.value {
+			copiedM[k] = v
+		}
+		m.mutex.RUnlock()
+		for k, v := range copiedM {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// AllLocked returns an iterator over all pairs of the key and value in the map.
+// Unlike All, the iteration holds the read lock of the map until the loop ends without copying the map,
+// so the loop body must not call any method of the map, and should be short.
+// Even a read like Get takes the read lock again, which deadlocks when a writer is waiting for the lock.
+func (m *Map[K, V]) AllLocked() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.mutex.RLock()
+		defer m.mutex.RUnlock()
+		for k, v := range m.value {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over all keys in the map.
+// Like All, the iteration copies the keys with the read lock when the loop starts and doesn't hold the lock.
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.mutex.RLock()
+		keys := make([]K, 0, len(m.value))
+		for k := range m.value {
+			keys = append(keys, k)
+		}
+		m.mutex.RUnlock()
+		for _, k := range keys {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over all values in the map.
+// Like All, the iteration copies the values with the read lock when the loop starts and doesn't hold the lock.
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.mutex.RLock()
+		values := make([]V, 0, len(m.value))
+		for _, v := range m.value {
+			values = append(values, v)
+		}
+		m.mutex.RUnlock()
+		for _, v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Sorted returns an iterator over all pairs of the key and value in ascending order of keys.
+// Like All, the iteration copies the map with the read lock when the loop starts and doesn't hold the lock,
+// so the loop body can call any methods of the map.
+func (m *MapString) Sorted() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		m.mutex.RLock()
+		entries := make([]mapStringEntry, 0, len(m.value))
+		for k, v := range m.value {
+			entries = append(entries, mapStringEntry{key: k, value: v})
+		}
+		m.mutex.RUnlock()
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+		for _, e := range entries {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package safe
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestMapString_All(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "hello": "world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set("zoo", "zoo")
+		wg.Done()
+	}()
+	go func() {
+		for k, v := range age.All() {
+			if k == "" || v == "" {
+				t.Errorf(`MapString.All() yields "%s", "%s"`, k, v)
+			}
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+	a := map[string]string{}
+	for k, v := range age.All() {
+		a[k] = v
+		// the snapshot iteration doesn't hold the lock.
+		age.Set(k+k, v)
+	}
+	exp := map[string]string{"foo": "bar", "hello": "world", "zoo": "zoo"}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("MapString.All() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestMapString_AllLocked(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "hello": "world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set("zoo", "zoo")
+		wg.Done()
+	}()
+	go func() {
+		for k, v := range age.AllLocked() {
+			if k == "" || v == "" {
+				t.Errorf(`MapString.AllLocked() yields "%s", "%s"`, k, v)
+			}
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+	a := map[string]string{}
+	for k, v := range age.AllLocked() {
+		a[k] = v
+	}
+	exp := map[string]string{"foo": "bar", "hello": "world", "zoo": "zoo"}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("MapString.AllLocked() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestMapString_AllLocked_break(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "hello": "world"})
+	n := 0
+	for range age.AllLocked() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fatalf("the loop body is called %d times, wanted %d", n, 1)
+	}
+	// the lock must be released after break.
+	age.Set("foo", "zoo")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("the panic must be propagated")
+			}
+		}()
+		for range age.AllLocked() {
+			panic("foo")
+		}
+	}()
+	// the lock must be released after panic.
+	age.Set("foo", "bar")
+}
+
+func TestMapString_Keys(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "hello": "world"})
+	a := []string{}
+	for k := range age.Keys() {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	exp := []string{"foo", "hello"}
+	for k := range age.Keys() {
+		// the snapshot iteration doesn't hold the lock.
+		age.Delete(k)
+	}
+	if n := age.Len(); n != 0 {
+		t.Fatalf("MapString.Len() = %d, wanted %d", n, 0)
+	}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("MapString.Keys() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestMapString_Values(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "hello": "world"})
+	a := []string{}
+	for v := range age.Values() {
+		a = append(a, v)
+	}
+	sort.Strings(a)
+	exp := []string{"bar", "world"}
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("MapString.Values() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestMapString_Sorted(t *testing.T) {
+	age := NewMapString(map[string]string{"c": "3", "a": "1", "b": "2"})
+	keys := []string{}
+	values := []string{}
+	for k, v := range age.Sorted() {
+		keys = append(keys, k)
+		values = append(values, v)
+		// the snapshot iteration doesn't hold the lock.
+		age.Set(k+k, v)
+	}
+	if exp := []string{"a", "b", "c"}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("MapString.Sorted() keys = %v, wanted %v", keys, exp)
+	}
+	if exp := []string{"1", "2", "3"}; !reflect.DeepEqual(values, exp) {
+		t.Fatalf("MapString.Sorted() values = %v, wanted %v", values, exp)
+	}
+	keys = keys[:0]
+	for k := range age.Sorted() {
+		keys = append(keys, k)
+		if len(keys) == 2 {
+			break
+		}
+	}
+	if exp := []string{"a", "aa"}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("MapString.Sorted() keys = %v, wanted %v", keys, exp)
+	}
+}
@@ -0,0 +1,101 @@
+package safe
+
+// MapReadLocked is a handle of Map which is passed to the function of Map.RLock.
+// MapReadLocked must not be used after the function returns.
+type MapReadLocked[K comparable, V any] struct {
+	m *Map[K, V]
+}
+
+// MapLocked is a handle of Map which is passed to the function of Map.Lock.
+// MapLocked must not be used after the function returns.
+type MapLocked[K comparable, V any] struct {
+	MapReadLocked[K, V]
+	// tx records the changes to notify them when the function returns.
+	tx *MapTx[K, V]
+}
+
+// MapStringReadLocked is a handle of MapString which is passed to the function of MapString.RLock.
+type MapStringReadLocked = MapReadLocked[string, string]
+
+// MapStringLocked is a handle of MapString which is passed to the function of MapString.Lock.
+type MapStringLocked = MapLocked[string, string]
+
+// Lock calls the function with a handle with lock.
+// The handle is used to read and write some keys together without the Unsafe methods.
+// Like Update, changes are notified to Watch and the wait methods after the function returns.
+// Unlike Update, changes aren't rolled back even if the function panics.
+// The function must not call the methods of the map.
+func (m *Map[K, V]) Lock(f func(v *MapLocked[K, V])) {
+	tx := &MapTx[K, V]{m: m, writable: true}
+	v := &MapLocked[K, V]{MapReadLocked: MapReadLocked[K, V]{m: m}, tx: tx}
+	m.mutex.Lock()
+	defer func() {
+		v.m = nil
+		v.tx = nil
+		events := tx.events()
+		for _, e := range events {
+			m.waiters.wake(e)
+		}
+		m.watchers.notify(m.mutex.Unlock, events...)
+	}()
+	f(v)
+}
+
+// RLock calls the function with a read-only handle with the read lock.
+// The function must not call any method of the map.
+// Even a read like Get takes the read lock again, which deadlocks when a writer is waiting for the lock.
+func (m *Map[K, V]) RLock(f func(v *MapReadLocked[K, V])) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	v := &MapReadLocked[K, V]{m: m}
+	defer func() {
+		v.m = nil
+	}()
+	f(v)
+}
+
+// Get gets a value from the map.
+func (v *MapReadLocked[K, V]) Get(k K) V {
+	return v.m.GetUnsafe(k)
+}
+
+// GetOk gets a value from the map.
+func (v *MapReadLocked[K, V]) GetOk(k K) (V, bool) {
+	return v.m.GetOkUnsafe(k)
+}
+
+// Has checks whether the map has the key.
+func (v *MapReadLocked[K, V]) Has(k K) bool {
+	return v.m.HasUnsafe(k)
+}
+
+// Len gets the length of the map.
+func (v *MapReadLocked[K, V]) Len() int {
+	return v.m.LenUnsafe()
+}
+
+// Range calls the function for all pairs of the key and value.
+// If the function returns false, the loop ends.
+func (v *MapReadLocked[K, V]) Range(f func(k K, v V) bool) {
+	v.m.RangeBUnsafe(f)
+}
+
+// Set sets the key and value to the map.
+func (v *MapLocked[K, V]) Set(k K, a V) {
+	v.tx.Set(k, a)
+}
+
+// SetDefault sets the key and value to the map if the map doesn't have the key and returns the value.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (v *MapLocked[K, V]) SetDefault(k K, a V) (V, bool) {
+	if b, ok := v.tx.GetOk(k); ok {
+		return b, true
+	}
+	v.tx.Set(k, a)
+	return a, false
+}
+
+// Delete deletes the key from the map.
+func (v *MapLocked[K, V]) Delete(k K) {
+	v.tx.Delete(k)
+}
@@ -0,0 +1,118 @@
+package safe
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMapString_Lock(t *testing.T) {
+	age := NewMapString(map[string]string{"count": "0"})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			age.Lock(func(v *MapStringLocked) {
+				n, err := strconv.Atoi(v.Get("count"))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				v.Set("count", strconv.Itoa(n+1))
+				if _, ok := v.SetDefault("first", "done"); !ok {
+					v.Delete("count")
+					v.Set("count", strconv.Itoa(n+1))
+				}
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Get("count"); a != "10" {
+		t.Fatalf(`MapString.Get("count") = %s, wanted 10`, a)
+	}
+	if a := age.Len(); a != 2 {
+		t.Fatalf("MapString.Len() = %d, wanted %d", a, 2)
+	}
+}
+
+func TestMapString_Lock_invalid(t *testing.T) {
+	age := NewMapString(map[string]string{})
+	var handle *MapStringLocked
+	age.Lock(func(v *MapStringLocked) {
+		handle = v
+	})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("the handle must not be used after the function returns")
+		}
+	}()
+	handle.Set("foo", "bar")
+}
+
+func TestMapString_Lock_notify(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "tmp": "1"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch := age.Watch(ctx)
+	done := make(chan string)
+	go func() {
+		v, err := age.WaitForKey(ctx, "result")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- v
+	}()
+	for age.waiters.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	age.Lock(func(v *MapStringLocked) {
+		v.Set("result", "done")
+		v.SetDefault("foo", "zoo")
+		v.Delete("tmp")
+	})
+	if a := <-done; a != "done" {
+		t.Fatalf(`MapString.WaitForKey("result") = %s, wanted done`, a)
+	}
+	exps := []MapStringEvent{
+		{Key: "result", New: "done"},
+		{Key: "tmp", Old: "1", Loaded: true, Deleted: true},
+	}
+	for _, exp := range exps {
+		if a := receiveWithTimeout(t, ch); a != exp {
+			t.Fatalf("received %+v, wanted %+v", a, exp)
+		}
+	}
+}
+
+func TestMapString_RLock(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "hello": "world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set("foo", "zoo")
+		wg.Done()
+	}()
+	go func() {
+		age.RLock(func(v *MapStringReadLocked) {
+			n := 0
+			v.Range(func(k, a string) bool {
+				if b, ok := v.GetOk(k); !ok || a != b {
+					t.Errorf(`MapStringReadLocked.GetOk("%s") = %s, %t, wanted %s, true`, k, b, ok, a)
+				}
+				n++
+				return true
+			})
+			if n != v.Len() || !v.Has("hello") {
+				t.Errorf("MapStringReadLocked.Len() = %d, wanted %d", v.Len(), n)
+			}
+		})
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Get("foo"); a != "zoo" {
+		t.Fatalf(`MapString.Get("foo") = %s, wanted zoo`, a)
+	}
+}
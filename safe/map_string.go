@@ -1,25 +1,31 @@
 package safe
 
 import (
-	"encoding/json"
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"sync"
+	"sort"
 )
 
 // MapString wraps map[string]string.
 // MapString must be created by NewMapString.
+// MapString is a Map[string, string], so all methods of Map are available.
 type MapString struct {
-	value map[string]string
-	mutex sync.RWMutex
+	Map[string, string]
 }
 
+// MapStringEvent is a change of a key, which is sent to the channel returned by MapString.Watch.
+type MapStringEvent = MapEvent[string, string]
+
 // NewMapString creates a MapString.
 // The argument `value` must not be nil.
 // Note that the argument `value` is holden in MapString, so don't read and write `value` out of the MapString.
 func NewMapString(value map[string]string) *MapString {
 	// To avoid the heap allocation, don't copy `value` and create a new map.
 	return &MapString{ // escapes to heap
-		value: value,
+		Map: Map[string, string]{
+			value: value,
+		},
 	}
 }
 
@@ -30,161 +36,104 @@ func (m *MapString) String() string {
 	return v
 }
 
-func (m *MapString) MarshalJSON() ([]byte, error) {
-	m.mutex.RLock()
-	b, err := json.Marshal(m.value)
-	m.mutex.RUnlock()
-	return b, err
-}
-
-func (m *MapString) UnmarshalJSON(buf []byte) error {
-	m.mutex.Lock()
-	err := json.Unmarshal(buf, &m.value)
-	m.mutex.Unlock()
-	return err
-}
-
-// Get gets a value from the map with lock.
-func (m *MapString) Get(k string) string {
-	m.mutex.RLock()
-	v := m.value[k]
-	m.mutex.RUnlock()
-	return v
+// MarshalText encodes the map as a JSON object, which is the same as MarshalJSON.
+func (m *MapString) MarshalText() ([]byte, error) {
+	return m.MarshalJSON()
 }
 
-// Get gets a value from the map with lock.
-func (m *MapString) GetOk(k string) (string, bool) {
-	m.mutex.RLock()
-	v, ok := m.value[k]
-	m.mutex.RUnlock()
-	return v, ok
+// UnmarshalText decodes the text encoded by MarshalText like UnmarshalJSON.
+// The existing keys which aren't in the text are kept.
+func (m *MapString) UnmarshalText(b []byte) error {
+	return m.UnmarshalJSON(b)
 }
 
-// Has checks whether the map has the key with lock.
-func (m *MapString) Has(k string) bool {
+// MarshalBinary encodes the map as the number of pairs followed by the pairs sorted by the key.
+// Each key and value is encoded as the length in uvarint followed by the bytes.
+func (m *MapString) MarshalBinary() ([]byte, error) {
 	m.mutex.RLock()
-	_, ok := m.value[k]
-	m.mutex.RUnlock()
-	return ok
-}
-
-// Len gets the length of the map with lock.
-func (m *MapString) Len() int {
-	m.mutex.RLock()
-	v := len(m.value)
+	keys := make([]string, 0, len(m.value))
+	size := binary.MaxVarintLen64
+	for k, v := range m.value {
+		keys = append(keys, k)
+		size += len(k) + len(v) + 2*binary.MaxVarintLen64
+	}
+	sort.Strings(keys)
+	b := binary.AppendUvarint(make([]byte, 0, size), uint64(len(keys)))
+	for _, k := range keys {
+		v := m.value[k]
+		b = binary.AppendUvarint(b, uint64(len(k)))
+		b = append(b, k...)
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		b = append(b, v...)
+	}
 	m.mutex.RUnlock()
-	return v
+	return b, nil
 }
 
-// Delete deletes the key from the map with lock.
-func (m *MapString) Delete(k string) {
-	m.mutex.Lock()
-	delete(m.value, k)
-	m.mutex.Unlock()
-}
-
-// DeleteR deletes the key from the map and returns the value with lock.
-func (m *MapString) DeleteR(k string) string {
-	m.mutex.Lock()
-	v := m.value[k]
-	delete(m.value, k)
-	m.mutex.Unlock()
-	return v
-}
-
-// DeleteROk deletes the key from the map and returns the value with lock.
-func (m *MapString) DeleteROk(k string) (string, bool) {
-	m.mutex.Lock()
-	v, ok := m.value[k]
-	if ok {
-		delete(m.value, k)
+// UnmarshalBinary decodes the data encoded by MarshalBinary and sets the pairs of the key and value to the map.
+// The existing keys which aren't in the data are kept.
+// If the data is broken, the map isn't changed.
+func (m *MapString) UnmarshalBinary(b []byte) error {
+	n, b, err := readUvarint(b)
+	if err != nil {
+		return err
 	}
-	m.mutex.Unlock()
-	return v, ok
-}
-
-// Set sets the key and value to the map with lock.
-func (m *MapString) Set(k, v string) {
-	m.mutex.Lock()
-	m.value[k] = v
-	m.mutex.Unlock()
-}
-
-// SetDefault sets the key and value to the map if the map doesn't have the key with lock.
-func (m *MapString) SetDefault(k, v string) {
-	m.mutex.Lock()
-	if _, ok := m.value[k]; !ok {
-		m.value[k] = v
+	// each pair takes 2 bytes at least.
+	if n > uint64(len(b))/2 {
+		return errInvalidMapStringBinary
 	}
-	m.mutex.Unlock()
+	v := make(map[string]string, n)
+	for i := uint64(0); i < n; i++ {
+		var k, a string
+		k, b, err = readBinaryString(b)
+		if err != nil {
+			return err
+		}
+		a, b, err = readBinaryString(b)
+		if err != nil {
+			return err
+		}
+		v[k] = a
+	}
+	if len(b) != 0 {
+		return errInvalidMapStringBinary
+	}
+	m.setAll(v)
+	return nil
 }
 
-// SetDefaultR sets the key and value to the map if the map doesn't have the key and returns the value with lock.
-// true is returned if the map has already haven the key and the value isn't updated.
-func (m *MapString) SetDefaultR(k, v string) (string, bool) {
-	m.mutex.Lock()
-	a, ok := m.value[k]
-	if !ok {
-		m.value[k] = v
-		a = v
-	}
-	m.mutex.Unlock()
-	return a, ok
+// GobEncode encodes the map in the same format as MarshalBinary.
+func (m *MapString) GobEncode() ([]byte, error) {
+	return m.MarshalBinary()
 }
 
-// SetFunc gets a value of the key from the map and calls the function and sets the returned value to the map with lock.
-// This is used to update the value based on the original value atomicaly.
-func (m *MapString) SetFunc(k string, f func(string, bool) string) {
-	m.mutex.Lock()
-	v, ok := m.value[k]
-	m.value[k] = f(v, ok)
-	m.mutex.Unlock()
+// GobDecode decodes the data encoded by GobEncode like UnmarshalBinary.
+func (m *MapString) GobDecode(b []byte) error {
+	return m.UnmarshalBinary(b)
 }
 
-// Range gets all pairs of the key and value from the map with lock and calls the function.
-func (m *MapString) Range(f func(k, v string)) {
-	m.mutex.RLock()
-	copiedM := make(map[string]string, len(m.value))
-	for k, v := range m.value {
-		copiedM[k] = v
-	}
-	m.mutex.RUnlock()
-	for k, v := range copiedM {
-		f(k, v)
+var errInvalidMapStringBinary = errors.New("safe: invalid binary data of MapString")
+
+func readUvarint(b []byte) (uint64, []byte, error) {
+	n, size := binary.Uvarint(b)
+	if size <= 0 {
+		return 0, nil, errInvalidMapStringBinary
 	}
+	return n, b[size:], nil
 }
 
-// RangeB gets all pairs of the key and value from the map with lock and calls the function.
-// If the function returns false, the loop ends.
-func (m *MapString) RangeB(f func(k, v string) bool) {
-	m.mutex.RLock()
-	copiedM := make(map[string]string, len(m.value))
-	for k, v := range m.value {
-		copiedM[k] = v
+func readBinaryString(b []byte) (string, []byte, error) {
+	n, b, err := readUvarint(b)
+	if err != nil {
+		return "", nil, err
 	}
-	m.mutex.RUnlock()
-
-	for k, v := range copiedM {
-		if !f(k, v) {
-			break
-		}
+	if n > uint64(len(b)) {
+		return "", nil, errInvalidMapStringBinary
 	}
+	return string(b[:n]), b[n:], nil
 }
 
 // Copy copies and creates a new MapString.
 func (m *MapString) Copy(target *MapString) {
-	m.mutex.RLock()
-	for k, v := range m.value {
-		target.value[k] = v
-	}
-	m.mutex.RUnlock()
-}
-
-// CopyData copies an internal map[string]string to target.
-func (m *MapString) CopyData(target map[string]string) {
-	m.mutex.RLock()
-	for k, v := range m.value {
-		target[k] = v
-	}
-	m.mutex.RUnlock()
+	m.Map.Copy(&target.Map)
 }
@@ -1,10 +1,16 @@
 package safe
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
 	"encoding/json"
+	"errors"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestMapString_String(t *testing.T) {
@@ -87,6 +93,55 @@ func TestMapString_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestMapString_encoding(t *testing.T) {
+	data := []struct {
+		title   string
+		marshal func(*MapString) ([]byte, error)
+		decode  func(*MapString, []byte) error
+	}{
+		{"text", (*MapString).MarshalText, (*MapString).UnmarshalText},
+		{"binary", (*MapString).MarshalBinary, (*MapString).UnmarshalBinary},
+		{"gob", func(m *MapString) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(m)
+			return buf.Bytes(), err
+		}, func(m *MapString, buf []byte) error {
+			return gob.NewDecoder(bytes.NewReader(buf)).Decode(m)
+		}},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			age := NewMapString(map[string]string{"foo": "bar", "": "empty", "hello": "\x00world\n"})
+			buf, err := d.marshal(age)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := NewMapString(map[string]string{"foo": "zoo", "kept": "value"})
+			if err := d.decode(a, buf); err != nil {
+				t.Fatal(err)
+			}
+			exp := map[string]string{"foo": "bar", "": "empty", "hello": "\x00world\n", "kept": "value"}
+			if !reflect.DeepEqual(a.value, exp) {
+				t.Fatalf("MapString.value = %v, wanted %v", a.value, exp)
+			}
+		})
+	}
+	age := NewMapString(map[string]string{"foo": "bar"})
+	buf, err := age.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range [][]byte{buf[:len(buf)-1], append(buf, 0), {0xff}} {
+		a := NewMapString(map[string]string{})
+		if err := a.UnmarshalBinary(b); err == nil {
+			t.Fatalf("MapString.UnmarshalBinary(%v) must fail", b)
+		}
+		if len(a.value) != 0 {
+			t.Fatalf("MapString.UnmarshalBinary() must not change the map with invalid data: %v", a.value)
+		}
+	}
+}
+
 func TestMapString_Get(t *testing.T) {
 	age := NewMapString(map[string]string{"foo": "bar"})
 
@@ -284,6 +339,32 @@ func TestMapString_SetFunc(t *testing.T) {
 	}
 }
 
+func TestMapString_SetFuncE(t *testing.T) {
+	age := NewMapString(map[string]string{"state": "draft"})
+	errTransition := errors.New("invalid transition")
+	publish := func(v string, ok bool) (string, error) {
+		if v != "draft" {
+			return "", errTransition
+		}
+		return "published", nil
+	}
+	if err := age.SetFuncE("state", publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE("state", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("MapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if a := age.Get("state"); a != "published" {
+		t.Fatalf(`MapString.Get("state") = %s, wanted published`, a)
+	}
+	if err := age.SetFuncE("foo", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("MapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if age.Has("foo") {
+		t.Fatal(`the key "foo" must not be added`)
+	}
+}
+
 func TestMapString_Range(t *testing.T) {
 	age := NewMapString(map[string]string{"foo": "bar"})
 	age.Range(func(k, v string) {
@@ -428,6 +509,217 @@ func TestMapString_SetDefaultR(t *testing.T) {
 	}
 }
 
+func TestMapString_Compute(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	data := []struct {
+		title string
+		key   string
+		v     string
+		op    ComputeOp
+		exp   string
+		expOk bool
+	}{
+		{title: "keep", key: "foo", v: "zoo", op: ComputeKeep, exp: "bar", expOk: true},
+		{title: "keep absent", key: "hello", v: "zoo", op: ComputeKeep},
+		{title: "store", key: "foo", v: "zoo", op: ComputeStore, exp: "zoo", expOk: true},
+		{title: "store absent", key: "hello", v: "world", op: ComputeStore, exp: "world", expOk: true},
+		{title: "delete", key: "foo", v: "zoo", op: ComputeDelete},
+		{title: "delete absent", key: "foo", v: "zoo", op: ComputeDelete},
+	}
+	for _, d := range data {
+		a, ok := age.Compute(d.key, func(old string, ok bool) (string, ComputeOp) {
+			return d.v, d.op
+		})
+		if a != d.exp || ok != d.expOk {
+			t.Fatalf(`%s: MapString.Compute() = "%s", %t, wanted "%s", %t`, d.title, a, ok, d.exp, d.expOk)
+		}
+		if b, ok := age.GetOk(d.key); b != d.exp || ok != d.expOk {
+			t.Fatalf(`%s: MapString.GetOk() = "%s", %t, wanted "%s", %t`, d.title, b, ok, d.exp, d.expOk)
+		}
+	}
+}
+
+func TestMapString_Compute_parallel(t *testing.T) {
+	age := NewMapString(map[string]string{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			age.Compute("count", func(old string, ok bool) (string, ComputeOp) {
+				return old + "!", ComputeStore
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Get("count"); a != "!!!!!!!!!!" {
+		t.Fatalf(`MapString.Get("count") = "%s", wanted "!!!!!!!!!!"`, a)
+	}
+}
+
+func TestMapString_ComputeIfAbsent(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	called := false
+	if a, ok := age.ComputeIfAbsent("foo", func() (string, bool) {
+		called = true
+		return "zoo", true
+	}); a != "bar" || !ok || called {
+		t.Fatalf(`MapString.ComputeIfAbsent("foo") = "%s", %t, wanted "bar", true without calling the function`, a, ok)
+	}
+	if a, ok := age.ComputeIfAbsent("hello", func() (string, bool) {
+		return "", false
+	}); a != "" || ok || age.Has("hello") {
+		t.Fatalf(`MapString.ComputeIfAbsent("hello") = "%s", %t, wanted "", false`, a, ok)
+	}
+	if a, ok := age.ComputeIfAbsent("hello", func() (string, bool) {
+		return "world", true
+	}); a != "world" || !ok {
+		t.Fatalf(`MapString.ComputeIfAbsent("hello") = "%s", %t, wanted "world", true`, a, ok)
+	}
+}
+
+func TestMapString_ComputeIfPresent(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	if a, ok := age.ComputeIfPresent("hello", func(old string) (string, bool) {
+		t.Fatal("the function must not be called")
+		return "", true
+	}); a != "" || ok || age.Has("hello") {
+		t.Fatalf(`MapString.ComputeIfPresent("hello") = "%s", %t, wanted "", false`, a, ok)
+	}
+	if a, ok := age.ComputeIfPresent("foo", func(old string) (string, bool) {
+		return old + "!", true
+	}); a != "bar!" || !ok {
+		t.Fatalf(`MapString.ComputeIfPresent("foo") = "%s", %t, wanted "bar!", true`, a, ok)
+	}
+	if a, ok := age.ComputeIfPresent("foo", func(old string) (string, bool) {
+		return "", false
+	}); a != "" || ok || age.Has("foo") {
+		t.Fatalf(`MapString.ComputeIfPresent("foo") = "%s", %t, wanted "", false`, a, ok)
+	}
+}
+
+func TestMapString_CompareAndSwap(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	if age.CompareAndSwap("foo", "zoo", "world") {
+		t.Fatal("CompareAndSwap must fail if the value isn't equal to old")
+	}
+	if !age.CompareAndSwap("foo", "bar", "world") {
+		t.Fatal("CompareAndSwap must succeed if the value is equal to old")
+	}
+	if a, _ := age.Swap("foo", "hello"); a != "world" {
+		t.Fatalf(`age.Swap("foo") = %s, wanted %s`, a, "world")
+	}
+	if !age.CompareAndDelete("foo", "hello") {
+		t.Fatal("CompareAndDelete must succeed if the value is equal to old")
+	}
+}
+
+func TestMapString_Watch(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := age.Watch(ctx)
+	age.Set("foo", "zoo")
+	age.SetFunc("hello", func(v string, ok bool) string {
+		return "world"
+	})
+	age.Delete("foo")
+	age.Delete("foo")
+	if err := json.Unmarshal([]byte(`{"hello":"!"}`), age); err != nil {
+		t.Fatal(err)
+	}
+	exps := []MapStringEvent{
+		{Key: "foo", Old: "bar", New: "zoo", Loaded: true},
+		{Key: "hello", New: "world"},
+		{Key: "foo", Old: "zoo", Loaded: true, Deleted: true},
+		{Key: "hello", Old: "world", New: "!", Loaded: true},
+	}
+	for _, exp := range exps {
+		if a := receiveWithTimeout(t, ch); a != exp {
+			t.Fatalf("received %+v, wanted %+v", a, exp)
+		}
+	}
+	select {
+	case a := <-ch:
+		t.Fatalf("received %+v, but no event is expected", a)
+	default:
+	}
+}
+
+func TestMapString_WaitForKey(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if a, err := age.WaitForKey(ctx, "foo"); err != nil || a != "bar" {
+		t.Fatalf(`MapString.WaitForKey("foo") = "%s", %v, wanted "bar", nil`, a, err)
+	}
+	var wg sync.WaitGroup
+	for _, f := range []func(){
+		func() { age.Set("job/1/result", "ok") },
+		func() { age.SetDefault("job/1/result", "ok") },
+		func() {
+			age.SetFunc("job/1/result", func(v string, ok bool) string { return "ok" })
+		},
+		func() {
+			if err := json.Unmarshal([]byte(`{"job/1/result":"ok"}`), age); err != nil {
+				t.Fatal(err)
+			}
+		},
+	} {
+		age.Delete("job/1/result")
+		wg.Add(1)
+		go func() {
+			if a, err := age.WaitForKey(ctx, "job/1/result"); err != nil || a != "ok" {
+				t.Errorf(`MapString.WaitForKey("job/1/result") = "%s", %v, wanted "ok", nil`, a, err)
+			}
+			wg.Done()
+		}()
+		for age.waiters.n.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		age.Set("foo", "zoo")
+		f()
+		wg.Wait()
+	}
+}
+
+func TestMapString_WaitForKey_canceled(t *testing.T) {
+	age := NewMapString(map[string]string{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := age.WaitForKey(ctx, "foo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf(`MapString.WaitForKey("foo") = %v, wanted %v`, err, context.DeadlineExceeded)
+	}
+	if a := age.waiters.n.Load(); a != 0 {
+		t.Fatalf("the number of waiters = %d, wanted %d", a, 0)
+	}
+}
+
+func TestMapString_WaitForChange(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if a, err := age.WaitForChange(ctx, "foo", "zoo"); err != nil || a != "bar" {
+		t.Fatalf(`MapString.WaitForChange("foo", "zoo") = "%s", %v, wanted "bar", nil`, a, err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if a, err := age.WaitForChange(ctx, "foo", "bar"); err != nil || a != "zoo" {
+			t.Errorf(`MapString.WaitForChange("foo", "bar") = "%s", %v, wanted "zoo", nil`, a, err)
+		}
+		wg.Done()
+	}()
+	for age.waiters.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// neither the same value nor the deletion wakes the waiter.
+	age.Set("foo", "bar")
+	age.Delete("foo")
+	age.Set("foo", "zoo")
+	wg.Wait()
+}
+
 func BenchmarkMapString_Set(b *testing.B) {
 	key := "foo"
 	age := NewMapString(map[string]string{key: "bar"})
@@ -0,0 +1,466 @@
+package safe
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMap_String(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Set("zoo", 2)
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	if !strings.HasPrefix(a, "Map{") {
+		t.Fatalf("Map.String() = %s, must start with 'Map{'", a)
+	}
+}
+
+func TestMap_MarshalJSON(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set("foo", 2)
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"foo":2}`
+
+	if string(b) != exp {
+		t.Fatalf("Map.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestMap_UnmarshalJSON(t *testing.T) {
+	age := NewMap(map[int64]int{1: 1})
+	var wg sync.WaitGroup
+	buf := []byte(`{"5":10}`)
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set(2, 2)
+		wg.Done()
+	}()
+	go func() {
+		err = json.Unmarshal(buf, age)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf, age); err != nil {
+		t.Fatal(err)
+	}
+	if len(age.value) != 3 {
+		t.Fatalf("len(age.value) = %d, wanted 3", len(age.value))
+	}
+	if age.value[5] != 10 {
+		t.Fatalf(`age.value[5] = %d, wanted %d`, age.value[5], 10)
+	}
+}
+
+func TestMap_Get(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Get("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", 2)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a := age.Get("foo")
+	if a != 2 {
+		t.Fatalf("Map.Get() = %d, wanted %d", a, 2)
+	}
+}
+
+func TestMap_GetOk(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.GetOk("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", 2)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a, ok := age.GetOk("foo")
+	if a != 2 {
+		t.Fatalf("Map.GetOk() = %d, wanted %d", a, 2)
+	}
+	if !ok {
+		t.Fatalf("Map.GetOk() = _, %t, wanted %t", ok, true)
+	}
+	if _, ok := age.GetOk("bar"); ok {
+		t.Fatalf("Map.GetOk() = _, %t, wanted %t", ok, false)
+	}
+}
+
+func TestMap_Has(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Has("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", 2)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if ok := age.Has("foo"); !ok {
+		t.Fatalf("Map.Has() = %t, wanted %t", ok, true)
+	}
+}
+
+func TestMap_Len(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Len()
+		wg.Done()
+	}()
+	go func() {
+		age.Set("bar", 2)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a := age.Len()
+	if a != 2 {
+		t.Fatalf("Map.Len() = %d, wanted %d", a, 2)
+	}
+}
+
+func TestMap_Delete(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Delete("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Delete("foo")
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a := len(age.value)
+	if a != 0 {
+		t.Fatalf("len(Map.value) = %d, wanted %d", a, 0)
+	}
+}
+
+func TestMap_DeleteR(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.DeleteR("zoo")
+		wg.Done()
+	}()
+	go func() {
+		age.DeleteR("zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a := age.DeleteR("foo")
+	if a != 1 {
+		t.Fatalf("a = %d, wanted %d", a, 1)
+	}
+}
+
+func TestMap_DeleteROk(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.DeleteROk("zoo")
+		wg.Done()
+	}()
+	go func() {
+		age.DeleteROk("zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a, ok := age.DeleteROk("foo")
+	if a != 1 {
+		t.Fatalf("a = %d, wanted %d", a, 1)
+	}
+	if !ok {
+		t.Fatalf("ok = %t, wanted %t", ok, true)
+	}
+}
+
+func TestMap_Set(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set("foo", 2)
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", 2)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a := age.value["foo"]
+	if a != 2 {
+		t.Fatalf("age.value['foo'] = %d, wanted %d", a, 2)
+	}
+}
+
+func TestMap_SetDefault(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.SetDefault("foo", 2)
+		wg.Done()
+	}()
+	go func() {
+		age.SetDefault("bar", 3)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if a := age.GetUnsafe("foo"); a != 1 {
+		t.Fatalf(`age.GetUnsafe("foo") = %d, wanted %d`, a, 1)
+	}
+	if a := age.GetUnsafe("bar"); a != 3 {
+		t.Fatalf(`age.GetUnsafe("bar") = %d, wanted %d`, a, 3)
+	}
+}
+
+func TestMap_SetDefaultR(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.SetDefaultR("foo", 2)
+		wg.Done()
+	}()
+	go func() {
+		age.SetDefaultR("bar", 3)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a, ok := age.SetDefaultR("foo", 4)
+	if a != 1 {
+		t.Fatalf(`age.SetDefaultR("foo") = %d, wanted %d`, a, 1)
+	}
+	if !ok {
+		t.Fatalf(`age.SetDefaultR("foo") = _, %t, wanted %t`, ok, true)
+	}
+	if a := age.GetUnsafe("bar"); a != 3 {
+		t.Fatalf(`age.GetUnsafe("bar") = %d, wanted %d`, a, 3)
+	}
+}
+
+func TestMap_SetFunc(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			age.SetFunc("foo", func(v int, ok bool) int {
+				return v + 1
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	a := age.value["foo"]
+	if a != 3 {
+		t.Fatalf("age.value['foo'] = %d, wanted %d", a, 3)
+	}
+}
+
+func TestMap_Swap(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	a, ok := age.Swap("foo", 2)
+	if a != 1 || !ok {
+		t.Fatalf(`Map.Swap("foo") = %d, %t, wanted %d, %t`, a, ok, 1, true)
+	}
+	a, ok = age.Swap("bar", 3)
+	if a != 0 || ok {
+		t.Fatalf(`Map.Swap("bar") = %d, %t, wanted %d, %t`, a, ok, 0, false)
+	}
+	if a := age.value["bar"]; a != 3 {
+		t.Fatalf("age.value['bar'] = %d, wanted %d", a, 3)
+	}
+}
+
+func TestMap_CompareAndSwap(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	var wg sync.WaitGroup
+	var swapped Int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			if age.CompareAndSwap("foo", 1, 2) {
+				swapped.Add(1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := swapped.Get(); a != 1 {
+		t.Fatalf("the number of successful CompareAndSwap = %d, wanted 1", a)
+	}
+	if a := age.value["foo"]; a != 2 {
+		t.Fatalf("age.value['foo'] = %d, wanted %d", a, 2)
+	}
+	if age.CompareAndSwap("bar", 0, 1) {
+		t.Fatal("CompareAndSwap must fail if the map doesn't have the key")
+	}
+}
+
+func TestMap_CompareAndDelete(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	if age.CompareAndDelete("foo", 2) {
+		t.Fatal("CompareAndDelete must fail if the value isn't equal to old")
+	}
+	if !age.CompareAndDelete("foo", 1) {
+		t.Fatal("CompareAndDelete must succeed if the value is equal to old")
+	}
+	if a := len(age.value); a != 0 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 0)
+	}
+}
+
+func TestMap_Range(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	age.Range(func(k string, v int) {
+		if k != "foo" {
+			t.Fatalf("k = %s, wanted %s", k, "foo")
+		}
+		if v != 1 {
+			t.Fatalf("v = %d, wanted %d", v, 1)
+		}
+		// Range doesn't hold the lock while the function is called.
+		age.Set("bar", 2)
+	})
+}
+
+func TestMap_RangeB(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1, "bar": 2})
+	cnt := 0
+	age.RangeB(func(k string, v int) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
+
+func TestMap_Copy(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	cp := NewMap(map[string]int{})
+	wg.Add(2)
+	go func() {
+		age.Copy(cp)
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", 1)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a := len(cp.value)
+	if a != 1 {
+		t.Fatalf("len(cp.value) = %d, wanted %d", a, 1)
+	}
+}
+
+func TestMap_CopyData(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+
+	var wg sync.WaitGroup
+	cp := map[string]int{}
+	wg.Add(2)
+	go func() {
+		age.CopyData(cp)
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", 1)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	a := len(cp)
+	if a != 1 {
+		t.Fatalf("len(cp) = %d, wanted %d", a, 1)
+	}
+}
+
+func BenchmarkMap_Set(b *testing.B) {
+	age := NewMap(map[string]int{"foo": 1})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a := age.Get("foo")
+		age.Set("foo", a+1)
+	}
+}
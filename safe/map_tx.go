@@ -0,0 +1,135 @@
+package safe
+
+// MapTx is a transaction of Map, which is passed to the function of Map.Update and Map.View.
+// MapTx must not be used after the function returns.
+type MapTx[K comparable, V any] struct {
+	m        *Map[K, V]
+	writable bool
+	// undo has the original values of the changed keys to roll back the transaction.
+	undo map[K]mapTxOrig[V]
+	// keys has the changed keys in the order of the first change.
+	keys []K
+}
+
+type mapTxOrig[V any] struct {
+	value V
+	ok    bool
+}
+
+// MapStringTx is a transaction of MapString.
+type MapStringTx = MapTx[string, string]
+
+// Update calls the function with a transaction under the write lock.
+// All changes in the transaction are rolled back if the function returns an error or panics,
+// and the error is returned.
+// Otherwise the changes are notified to Watch, WaitForKey and WaitForChange after the function returns.
+// The function must not call the methods of the map, because the lock is already held.
+func (m *Map[K, V]) Update(f func(tx *MapTx[K, V]) error) error {
+	tx := &MapTx[K, V]{m: m, writable: true}
+	m.mutex.Lock()
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+			m.mutex.Unlock()
+		}
+	}()
+	if err := f(tx); err != nil {
+		return err
+	}
+	committed = true
+	events := tx.events()
+	for _, e := range events {
+		m.waiters.wake(e)
+	}
+	m.watchers.notify(m.mutex.Unlock, events...)
+	return nil
+}
+
+// View calls the function with a read-only transaction under the read lock and returns the error of the function.
+// Set and Delete of the transaction panic.
+// The function must not call any method of the map, because the read lock is held.
+// Even a read like Get takes the read lock again, which deadlocks when a writer is waiting for the lock.
+func (m *Map[K, V]) View(f func(tx *MapTx[K, V]) error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return f(&MapTx[K, V]{m: m})
+}
+
+// Get gets a value from the map in the transaction.
+func (tx *MapTx[K, V]) Get(k K) V {
+	return tx.m.value[k]
+}
+
+// GetOk gets a value from the map in the transaction.
+func (tx *MapTx[K, V]) GetOk(k K) (V, bool) {
+	v, ok := tx.m.value[k]
+	return v, ok
+}
+
+// Has checks whether the map has the key in the transaction.
+func (tx *MapTx[K, V]) Has(k K) bool {
+	_, ok := tx.m.value[k]
+	return ok
+}
+
+// Len gets the length of the map in the transaction.
+func (tx *MapTx[K, V]) Len() int {
+	return len(tx.m.value)
+}
+
+// Set sets the key and value to the map in the transaction.
+func (tx *MapTx[K, V]) Set(k K, v V) {
+	tx.record(k)
+	tx.m.value[k] = v
+}
+
+// Delete deletes the key from the map in the transaction.
+func (tx *MapTx[K, V]) Delete(k K) {
+	tx.record(k)
+	delete(tx.m.value, k)
+}
+
+// record saves the original value of the key before the first change.
+func (tx *MapTx[K, V]) record(k K) {
+	if !tx.writable {
+		panic("safe: the map is changed in the read-only transaction")
+	}
+	if _, ok := tx.undo[k]; ok {
+		return
+	}
+	if tx.undo == nil {
+		tx.undo = map[K]mapTxOrig[V]{}
+	}
+	v, ok := tx.m.value[k]
+	tx.undo[k] = mapTxOrig[V]{value: v, ok: ok}
+	tx.keys = append(tx.keys, k)
+}
+
+func (tx *MapTx[K, V]) rollback() {
+	for k, orig := range tx.undo {
+		if orig.ok {
+			tx.m.value[k] = orig.value
+			continue
+		}
+		delete(tx.m.value, k)
+	}
+}
+
+// events returns the changes of the transaction.
+// The key which doesn't exist both before and after the transaction is skipped.
+func (tx *MapTx[K, V]) events() []MapEvent[K, V] {
+	if tx.m.watchers.n.Load() == 0 && tx.m.waiters.n.Load() == 0 {
+		return nil
+	}
+	events := make([]MapEvent[K, V], 0, len(tx.keys))
+	for _, k := range tx.keys {
+		orig := tx.undo[k]
+		v, ok := tx.m.value[k]
+		if !orig.ok && !ok {
+			continue
+		}
+		events = append(events, MapEvent[K, V]{Key: k, Old: orig.value, New: v, Loaded: orig.ok, Deleted: !ok})
+	}
+	return events
+}
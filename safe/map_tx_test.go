@@ -0,0 +1,149 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestMapString_Update(t *testing.T) {
+	age := NewMapString(map[string]string{"from": "1", "to": "0"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	move := func() {
+		if err := age.Update(func(tx *MapStringTx) error {
+			from, err := strconv.Atoi(tx.Get("from"))
+			if err != nil {
+				return err
+			}
+			to, err := strconv.Atoi(tx.Get("to"))
+			if err != nil {
+				return err
+			}
+			tx.Set("from", strconv.Itoa(from-1))
+			tx.Set("to", strconv.Itoa(to+1))
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}
+	go move()
+	go move()
+	wg.Wait()
+	exp := map[string]string{"from": "-1", "to": "2"}
+	a := map[string]string{}
+	age.CopyData(a)
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("MapString.Update() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestMapString_Update_rollback(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar", "hello": "world"})
+	errRollback := errors.New("rollback")
+	err := age.Update(func(tx *MapStringTx) error {
+		tx.Set("foo", "zoo")
+		tx.Set("foo", "yoo")
+		tx.Delete("hello")
+		tx.Set("new", "value")
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("MapString.Update() = %v, wanted %v", err, errRollback)
+	}
+	exp := map[string]string{"foo": "bar", "hello": "world"}
+	a := map[string]string{}
+	age.CopyData(a)
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("MapString.Update() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestMapString_Update_panic(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("the panic must be propagated")
+			}
+		}()
+		_ = age.Update(func(tx *MapStringTx) error {
+			tx.Set("foo", "zoo")
+			panic("update")
+		})
+	}()
+	// the lock must be released and the change must be rolled back.
+	if a := age.Get("foo"); a != "bar" {
+		t.Fatalf(`MapString.Get("foo") = %s, wanted bar`, a)
+	}
+}
+
+func TestMapString_Update_watch(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := age.Watch(ctx)
+	if err := age.Update(func(tx *MapStringTx) error {
+		tx.Set("hello", "world")
+		tx.Delete("foo")
+		tx.Set("tmp", "1")
+		tx.Delete("tmp")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	exps := []MapStringEvent{
+		{Key: "hello", New: "world"},
+		{Key: "foo", Old: "bar", Loaded: true, Deleted: true},
+	}
+	for _, exp := range exps {
+		if a := receiveWithTimeout(t, ch); a != exp {
+			t.Fatalf("received %+v, wanted %+v", a, exp)
+		}
+	}
+	select {
+	case a := <-ch:
+		t.Fatalf("received %+v, but no event is expected", a)
+	default:
+	}
+}
+
+func TestMapString_View(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set("foo", "zoo")
+		wg.Done()
+	}()
+	go func() {
+		if err := age.View(func(tx *MapStringTx) error {
+			if tx.Has("foo") != (tx.Len() == 1) {
+				t.Error("MapStringTx.Has() and MapStringTx.Len() are inconsistent")
+			}
+			return nil
+		}); err != nil {
+			t.Error(err)
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("MapStringTx.Set() must panic in View")
+			}
+		}()
+		_ = age.View(func(tx *MapStringTx) error {
+			tx.Set("foo", "bar")
+			return nil
+		})
+	}()
+	if a := age.Get("foo"); a != "zoo" {
+		t.Fatalf(`MapString.Get("foo") = %s, wanted zoo`, a)
+	}
+}
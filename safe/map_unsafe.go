@@ -0,0 +1,81 @@
+package safe
+
+// GetUnsafe gets a value from the map without lock.
+func (m *Map[K, V]) GetUnsafe(k K) V {
+	return m.value[k]
+}
+
+// GetOkUnsafe gets a value from the map without lock.
+func (m *Map[K, V]) GetOkUnsafe(k K) (V, bool) {
+	v, ok := m.value[k]
+	return v, ok
+}
+
+// HasUnsafe checks whether the map has the key without lock.
+func (m *Map[K, V]) HasUnsafe(k K) bool {
+	_, ok := m.value[k]
+	return ok
+}
+
+// LenUnsafe gets the length of the map without lock.
+func (m *Map[K, V]) LenUnsafe() int {
+	return len(m.value)
+}
+
+// DeleteUnsafe deletes the key from the map without lock.
+func (m *Map[K, V]) DeleteUnsafe(k K) {
+	delete(m.value, k)
+}
+
+// SetUnsafe sets the key and value to the map without lock.
+func (m *Map[K, V]) SetUnsafe(k K, v V) {
+	m.value[k] = v
+}
+
+// SetDefaultUnsafe sets the key and value to the map if the map doesn't have the key without lock.
+func (m *Map[K, V]) SetDefaultUnsafe(k K, v V) {
+	if _, ok := m.value[k]; !ok {
+		m.value[k] = v
+	}
+}
+
+// SetDefaultRUnsafe sets the key and value to the map if the map doesn't have the key without lock.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (m *Map[K, V]) SetDefaultRUnsafe(k K, v V) (V, bool) {
+	if a, ok := m.value[k]; ok {
+		return a, true
+	}
+	m.value[k] = v
+	return v, false
+}
+
+// RangeUnsafe gets all pairs of the key and value from the map and call the function without lock.
+func (m *Map[K, V]) RangeUnsafe(f func(k K, v V)) {
+	for k, v := range m.value {
+		f(k, v)
+	}
+}
+
+// RangeBUnsafe gets pairs of the key and value from the map and call the function without lock.
+// If the function returns false, the loop ends.
+func (m *Map[K, V]) RangeBUnsafe(f func(k K, v V) bool) {
+	for k, v := range m.value {
+		if !f(k, v) {
+			break
+		}
+	}
+}
+
+// CopyUnsafe copies all pairs of the key and value to target without lock.
+func (m *Map[K, V]) CopyUnsafe(target *Map[K, V]) {
+	for k, v := range m.value {
+		target.value[k] = v
+	}
+}
+
+// CopyDataUnsafe copies an internal map[K]V to target without lock.
+func (m *Map[K, V]) CopyDataUnsafe(target map[K]V) {
+	for k, v := range m.value {
+		target[k] = v
+	}
+}
@@ -0,0 +1,120 @@
+package safe
+
+import (
+	"testing"
+)
+
+func TestMap_GetUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	a := age.GetUnsafe("foo")
+	if a != 1 {
+		t.Fatalf("Map.GetUnsafe() = %d, wanted %d", a, 1)
+	}
+}
+
+func TestMap_GetOkUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	a, ok := age.GetOkUnsafe("foo")
+	if a != 1 {
+		t.Fatalf("Map.GetOkUnsafe() = %d, wanted %d", a, 1)
+	}
+	if !ok {
+		t.Fatalf("Map.GetOkUnsafe() = _, %t, wanted %t", ok, true)
+	}
+}
+
+func TestMap_HasUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	if ok := age.HasUnsafe("foo"); !ok {
+		t.Fatalf("Map.HasUnsafe() = %t, wanted %t", ok, true)
+	}
+}
+
+func TestMap_LenUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	if a := age.LenUnsafe(); a != 1 {
+		t.Fatalf("Map.LenUnsafe() = %d, wanted %d", a, 1)
+	}
+}
+
+func TestMap_DeleteUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	age.DeleteUnsafe("foo")
+	if a := len(age.value); a != 0 {
+		t.Fatalf("len(Map.value) = %d, wanted %d", a, 0)
+	}
+}
+
+func TestMap_SetUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	age.SetUnsafe("foo", 2)
+	if a := age.value["foo"]; a != 2 {
+		t.Fatalf("age.value['foo'] = %d, wanted %d", a, 2)
+	}
+}
+
+func TestMap_SetDefaultUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	age.SetDefaultUnsafe("foo", 2)
+	if a := age.value["foo"]; a != 1 {
+		t.Fatalf("age.value['foo'] = %d, wanted %d", a, 1)
+	}
+	age.SetDefaultUnsafe("zoo", 3)
+	if a := age.value["zoo"]; a != 3 {
+		t.Fatalf("age.value['zoo'] = %d, wanted %d", a, 3)
+	}
+}
+
+func TestMap_SetDefaultRUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	a, ok := age.SetDefaultRUnsafe("foo", 2)
+	if a != 1 || !ok {
+		t.Fatalf("age.SetDefaultRUnsafe('foo') = %d, %t, wanted %d, %t", a, ok, 1, true)
+	}
+	a, ok = age.SetDefaultRUnsafe("zoo", 3)
+	if a != 3 || ok {
+		t.Fatalf("age.SetDefaultRUnsafe('zoo') = %d, %t, wanted %d, %t", a, ok, 3, false)
+	}
+}
+
+func TestMap_RangeUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	age.RangeUnsafe(func(k string, v int) {
+		if k != "foo" {
+			t.Fatalf("k = %s, wanted %s", k, "foo")
+		}
+		if v != 1 {
+			t.Fatalf("v = %d, wanted %d", v, 1)
+		}
+	})
+}
+
+func TestMap_RangeBUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1, "bar": 2})
+	cnt := 0
+	age.RangeBUnsafe(func(k string, v int) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
+
+func TestMap_CopyUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	cp := NewMap(map[string]int{})
+	age.CopyUnsafe(cp)
+	if a := len(cp.value); a != 1 {
+		t.Fatalf("len(cp.value) = %d, wanted %d", a, 1)
+	}
+}
+
+func TestMap_CopyDataUnsafe(t *testing.T) {
+	age := NewMap(map[string]int{"foo": 1})
+	cp := make(map[string]int, 1)
+	age.CopyDataUnsafe(cp)
+	if a := len(cp); a != 1 {
+		t.Fatalf("len(cp) = %d, wanted %d", a, 1)
+	}
+}
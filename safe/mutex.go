@@ -0,0 +1,9 @@
+//go:build !safedebug
+
+package safe
+
+import "sync"
+
+// rwMutex is the mutex of the containers.
+// With the build tag safedebug, rwMutex detects reentrant locking. See mutex_debug.go.
+type rwMutex = sync.RWMutex
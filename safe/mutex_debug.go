@@ -0,0 +1,108 @@
+//go:build safedebug
+
+package safe
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rwMutex is sync.RWMutex which detects reentrant locking.
+// sync.RWMutex isn't reentrant, so a callback which calls a method of the container running the callback deadlocks.
+// With the build tag safedebug, rwMutex records the goroutines holding the lock
+// and panics if a goroutine locks the mutex which it already holds.
+// This is slow and should be used only for testing and debugging.
+type rwMutex struct {
+	sync.RWMutex
+	// holders has the methods holding the lock by goroutine id.
+	holders map[uint64]string
+	mutex   sync.Mutex
+}
+
+var pkgPrefix = reflect.TypeOf(rwMutex{}).PkgPath() + "."
+
+func (m *rwMutex) Lock() {
+	m.check()
+	m.RWMutex.Lock()
+	m.hold()
+}
+
+func (m *rwMutex) Unlock() {
+	m.mutex.Lock()
+	m.holders = nil
+	m.mutex.Unlock()
+	m.RWMutex.Unlock()
+}
+
+func (m *rwMutex) RLock() {
+	m.check()
+	m.RWMutex.RLock()
+	m.hold()
+}
+
+func (m *rwMutex) RUnlock() {
+	id := goroutineID()
+	m.mutex.Lock()
+	delete(m.holders, id)
+	m.mutex.Unlock()
+	m.RWMutex.RUnlock()
+}
+
+// check panics if the current goroutine already holds the lock.
+func (m *rwMutex) check() {
+	id := goroutineID()
+	m.mutex.Lock()
+	holder, ok := m.holders[id]
+	m.mutex.Unlock()
+	if ok {
+		panic(fmt.Sprintf(
+			"safe: %s is called while %s of the same container holds the lock in the same goroutine. The lock isn't reentrant, so this would deadlock",
+			callerMethod(), holder))
+	}
+}
+
+// hold records the current goroutine and the method as a holder of the lock.
+func (m *rwMutex) hold() {
+	id := goroutineID()
+	method := callerMethod()
+	m.mutex.Lock()
+	if m.holders == nil {
+		m.holders = map[uint64]string{}
+	}
+	m.holders[id] = method
+	m.mutex.Unlock()
+}
+
+// goroutineID returns the id of the current goroutine, which is parsed from the stack trace.
+func goroutineID() uint64 {
+	var buf [64]byte
+	s := strings.TrimPrefix(string(buf[:runtime.Stack(buf[:], false)]), "goroutine ")
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+	id, _ := strconv.ParseUint(s, 10, 64)
+	return id
+}
+
+// callerMethod returns the outermost method of this package in the call stack,
+// which is the method called by the user.
+func callerMethod() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	name := ""
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) || strings.HasSuffix(f.File, "_test.go") {
+			break
+		}
+		name = f.Function
+		if !more {
+			break
+		}
+	}
+	return strings.TrimPrefix(name, pkgPrefix)
+}
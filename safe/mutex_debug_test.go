@@ -0,0 +1,110 @@
+//go:build safedebug
+
+package safe
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRWMutex_reentrant(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	count := &Value[int]{}
+	name := &String{}
+	data := []struct {
+		title string
+		call  func()
+		exps  []string
+	}{
+		{
+			title: "MapString.SetFunc calls Get",
+			call: func() {
+				age.SetFunc("foo", func(v string, ok bool) string {
+					return age.Get("foo")
+				})
+			},
+			exps: []string{").Get is called while", ").SetFunc of the same container"},
+		},
+		{
+			title: "MapString.View calls Get",
+			call: func() {
+				_ = age.View(func(tx *MapStringTx) error {
+					age.Get("foo")
+					return nil
+				})
+			},
+			exps: []string{").Get is called while", ").View of the same container"},
+		},
+		{
+			title: "MapString.AllLocked calls Get",
+			call: func() {
+				for k := range age.AllLocked() {
+					age.Get(k)
+				}
+			},
+			exps: []string{").Get is called while"},
+		},
+		{
+			title: "Value.SetFunc calls Set",
+			call: func() {
+				count.SetFunc(func(v int) int {
+					count.Set(1)
+					return v
+				})
+			},
+			exps: []string{").Set is called while", ").SetFunc of the same container"},
+		},
+		{
+			title: "String.Lock calls Set",
+			call: func() {
+				name.Lock(func(v *StringLocked) {
+					name.Set("foo")
+				})
+			},
+			exps: []string{"(*String).Set is called while (*String).Lock"},
+		},
+		{
+			title: "LockAll and Set",
+			call: func() {
+				Atomically(func() {
+					name.Set("foo")
+				}, age, name)
+			},
+			exps: []string{"(*String).Set is called while Atomically"},
+		},
+	}
+	for _, d := range data {
+		d := d
+		t.Run(d.title, func(t *testing.T) {
+			var pErr *PanicError
+			if err := Recover(d.call); !errors.As(err, &pErr) {
+				t.Fatalf("Recover() = %v, wanted the panic", err)
+			}
+			msg, _ := pErr.Value.(string)
+			for _, exp := range d.exps {
+				if !strings.Contains(msg, exp) {
+					t.Fatalf("the panic message %q must contain %q", msg, exp)
+				}
+			}
+			// the lock must be released.
+			age.Set("foo", "bar")
+			count.Set(1)
+			name.Set("foo")
+		})
+	}
+}
+
+func TestRWMutex_RLock(t *testing.T) {
+	age := NewMapString(map[string]string{"foo": "bar"})
+	done := make(chan struct{})
+	age.RLock(func(v *MapStringReadLocked) {
+		// other goroutines can take the read lock.
+		go func() {
+			age.Get("foo")
+			close(done)
+		}()
+		<-done
+	})
+	age.Set("foo", "zoo")
+}
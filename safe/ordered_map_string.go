@@ -0,0 +1,427 @@
+package safe
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// OrderedMapString wraps map[string]string and remembers the insertion order of keys.
+// Range, RangeB, Keys, String and MarshalJSON use the insertion order,
+// and UnmarshalJSON adds keys in the order of the JSON document.
+// Updating the value of an existing key doesn't change the order.
+//
+// OrderedMapString must be used as the pointer because OrderedMapString has sync.RWMutex as a private field.
+// The zero value is an empty map.
+type OrderedMapString struct {
+	value map[string]*list.Element
+	order list.List
+	mutex rwMutex
+}
+
+type mapStringEntry struct {
+	key   string
+	value string
+}
+
+// NewOrderedMapString creates an OrderedMapString.
+// The pairs of `keys` and `values` are added in order.
+// NewOrderedMapString panics if the lengths of `keys` and `values` are different.
+func NewOrderedMapString(keys, values []string) *OrderedMapString {
+	if len(keys) != len(values) {
+		panic("safe: the lengths of keys and values are different")
+	}
+	m := &OrderedMapString{ // escapes to heap
+		value: make(map[string]*list.Element, len(keys)),
+	}
+	for i, k := range keys {
+		m.setUnsafe(k, values[i])
+	}
+	return m
+}
+
+func orderedEntry(e *list.Element) *mapStringEntry {
+	return e.Value.(*mapStringEntry)
+}
+
+func (m *OrderedMapString) getUnsafe(k string) (string, bool) {
+	if e, ok := m.value[k]; ok {
+		return orderedEntry(e).value, true
+	}
+	return "", false
+}
+
+func (m *OrderedMapString) setUnsafe(k, v string) {
+	if e, ok := m.value[k]; ok {
+		orderedEntry(e).value = v
+		return
+	}
+	if m.value == nil {
+		m.value = map[string]*list.Element{}
+	}
+	m.value[k] = m.order.PushBack(&mapStringEntry{key: k, value: v})
+}
+
+func (m *OrderedMapString) deleteUnsafe(k string) (string, bool) {
+	e, ok := m.value[k]
+	if !ok {
+		return "", false
+	}
+	delete(m.value, k)
+	m.order.Remove(e)
+	return orderedEntry(e).value, true
+}
+
+// entries copies all pairs of the key and value in order.
+// The caller must hold the lock.
+func (m *OrderedMapString) entries() []mapStringEntry {
+	ret := make([]mapStringEntry, 0, len(m.value))
+	for e := m.order.Front(); e != nil; e = e.Next() {
+		ret = append(ret, *orderedEntry(e))
+	}
+	return ret
+}
+
+func (m *OrderedMapString) String() string {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	pairs := make([]string, len(entries))
+	for i, e := range entries {
+		pairs[i] = e.key + ":" + e.value
+	}
+	return "OrderedMapString{" + fmt.Sprintf("%v", pairs) + "}"
+}
+
+// MarshalJSON encodes the map as a JSON object whose keys are in the insertion order.
+func (m *OrderedMapString) MarshalJSON() ([]byte, error) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	return encodeOrderedObject(entries)
+}
+
+// UnmarshalJSON decodes a JSON object and adds the pairs of the key and value in the order of the document.
+// Like MapString, the existing keys which aren't in the document are kept.
+func (m *OrderedMapString) UnmarshalJSON(buf []byte) error {
+	entries, err := decodeOrderedObject(buf)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	for _, e := range entries {
+		m.setUnsafe(e.key, e.value)
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+// encodeOrderedObject encodes pairs of the key and value as a JSON object whose keys are in the given order.
+func encodeOrderedObject(entries []mapStringEntry) ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, e := range entries {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(e.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeOrderedObject decodes a JSON object to pairs of the key and value in the order of the document.
+func decodeOrderedObject(buf []byte) ([]mapStringEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, checkEOF(dec)
+	}
+	if token != json.Delim('{') {
+		return nil, errors.New("safe: OrderedMapString must be decoded from a JSON object")
+	}
+	entries := []mapStringEntry{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		k, ok := token.(string)
+		if !ok {
+			return nil, errors.New("safe: a key of a JSON object must be a string")
+		}
+		var v string
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		entries = append(entries, mapStringEntry{key: k, value: v})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if err := checkEOF(dec); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// checkEOF returns an error if the decoder has data after the JSON value, like json.Unmarshal.
+func checkEOF(dec *json.Decoder) error {
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("safe: invalid data after the JSON value")
+	}
+	return nil
+}
+
+// Get gets a value from the map with lock.
+func (m *OrderedMapString) Get(k string) string {
+	m.mutex.RLock()
+	v, _ := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return v
+}
+
+// GetOk gets a value from the map with lock.
+func (m *OrderedMapString) GetOk(k string) (string, bool) {
+	m.mutex.RLock()
+	v, ok := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return v, ok
+}
+
+// Has checks whether the map has the key with lock.
+func (m *OrderedMapString) Has(k string) bool {
+	m.mutex.RLock()
+	_, ok := m.value[k]
+	m.mutex.RUnlock()
+	return ok
+}
+
+// Len gets the length of the map with lock.
+func (m *OrderedMapString) Len() int {
+	m.mutex.RLock()
+	v := len(m.value)
+	m.mutex.RUnlock()
+	return v
+}
+
+// Keys returns all keys in order with lock.
+func (m *OrderedMapString) Keys() []string {
+	m.mutex.RLock()
+	keys := make([]string, 0, len(m.value))
+	for e := m.order.Front(); e != nil; e = e.Next() {
+		keys = append(keys, orderedEntry(e).key)
+	}
+	m.mutex.RUnlock()
+	return keys
+}
+
+// Delete deletes the key from the map with lock.
+func (m *OrderedMapString) Delete(k string) {
+	m.mutex.Lock()
+	m.deleteUnsafe(k)
+	m.mutex.Unlock()
+}
+
+// DeleteR deletes the key from the map and returns the value with lock.
+func (m *OrderedMapString) DeleteR(k string) string {
+	m.mutex.Lock()
+	v, _ := m.deleteUnsafe(k)
+	m.mutex.Unlock()
+	return v
+}
+
+// DeleteROk deletes the key from the map and returns the value with lock.
+func (m *OrderedMapString) DeleteROk(k string) (string, bool) {
+	m.mutex.Lock()
+	v, ok := m.deleteUnsafe(k)
+	m.mutex.Unlock()
+	return v, ok
+}
+
+// Set sets the key and value to the map with lock.
+// A new key is added to the back.
+func (m *OrderedMapString) Set(k, v string) {
+	m.mutex.Lock()
+	m.setUnsafe(k, v)
+	m.mutex.Unlock()
+}
+
+// SetDefault sets the key and value to the map if the map doesn't have the key with lock.
+func (m *OrderedMapString) SetDefault(k, v string) {
+	m.mutex.Lock()
+	if _, ok := m.value[k]; !ok {
+		m.setUnsafe(k, v)
+	}
+	m.mutex.Unlock()
+}
+
+// SetDefaultR sets the key and value to the map if the map doesn't have the key and returns the value with lock.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (m *OrderedMapString) SetDefaultR(k, v string) (string, bool) {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	if !ok {
+		m.setUnsafe(k, v)
+		a = v
+	}
+	m.mutex.Unlock()
+	return a, ok
+}
+
+// SetFunc gets a value of the key from the map and calls the function and sets the returned value to the map with lock.
+// This is used to update the value based on the original value atomicaly.
+func (m *OrderedMapString) SetFunc(k string, f func(string, bool) string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, ok := m.getUnsafe(k)
+	m.setUnsafe(k, f(v, ok))
+}
+
+// SetFuncE is like SetFunc but the map isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the map isn't updated and the panic is returned as *PanicError.
+func (m *OrderedMapString) SetFuncE(k string, f func(string, bool) (string, error)) error {
+	f = recoverMapFunc(f)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, ok := m.getUnsafe(k)
+	a, err := f(v, ok)
+	if err != nil {
+		return err
+	}
+	m.setUnsafe(k, a)
+	return nil
+}
+
+// Swap sets the key and value to the map and returns the previous value with lock.
+// true is returned if the map has had the key.
+func (m *OrderedMapString) Swap(k, v string) (string, bool) {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	m.setUnsafe(k, v)
+	m.mutex.Unlock()
+	return a, ok
+}
+
+// CompareAndSwap sets the value of the key only if the map has the key and the current value is equal to old with lock.
+// true is returned if the value is updated.
+func (m *OrderedMapString) CompareAndSwap(k, old, v string) bool {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	ok = ok && a == old
+	if ok {
+		m.setUnsafe(k, v)
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// CompareAndDelete deletes the key only if the current value is equal to old with lock.
+// true is returned if the key is deleted.
+func (m *OrderedMapString) CompareAndDelete(k, old string) bool {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	ok = ok && a == old
+	if ok {
+		m.deleteUnsafe(k)
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// MoveToFront moves the key to the front with lock.
+// false is returned if the map doesn't have the key.
+func (m *OrderedMapString) MoveToFront(k string) bool {
+	m.mutex.Lock()
+	e, ok := m.value[k]
+	if ok {
+		m.order.MoveToFront(e)
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// MoveToBack moves the key to the back with lock.
+// false is returned if the map doesn't have the key.
+func (m *OrderedMapString) MoveToBack(k string) bool {
+	m.mutex.Lock()
+	e, ok := m.value[k]
+	if ok {
+		m.order.MoveToBack(e)
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// Range gets all pairs of the key and value from the map with lock and calls the function in order.
+func (m *OrderedMapString) Range(f func(k, v string)) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		f(e.key, e.value)
+	}
+}
+
+// RangeB gets all pairs of the key and value from the map with lock and calls the function in order.
+// If the function returns false, the loop ends.
+func (m *OrderedMapString) RangeB(f func(k, v string) bool) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			break
+		}
+	}
+}
+
+// Copy copies all pairs of the key and value to target in order.
+func (m *OrderedMapString) Copy(target *OrderedMapString) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	target.mutex.Lock()
+	for _, e := range entries {
+		target.setUnsafe(e.key, e.value)
+	}
+	target.mutex.Unlock()
+}
+
+// CopyData copies all pairs of the key and value to target.
+func (m *OrderedMapString) CopyData(target map[string]string) {
+	m.mutex.RLock()
+	for k, e := range m.value {
+		target[k] = orderedEntry(e).value
+	}
+	m.mutex.RUnlock()
+}
+
+// SaveFile encodes the OrderedMapString by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (m *OrderedMapString) SaveFile(path string) error {
+	return saveFile(path, m)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (m *OrderedMapString) LoadFile(path string) error {
+	return loadFile(path, m)
+}
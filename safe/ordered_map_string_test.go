@@ -0,0 +1,348 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestNewOrderedMapString(t *testing.T) {
+	age := NewOrderedMapString([]string{"zoo", "foo"}, []string{"world", "bar"})
+	exp := []string{"zoo", "foo"}
+	if a := age.Keys(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("OrderedMapString.Keys() = %v, wanted %v", a, exp)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewOrderedMapString must panic if the lengths are different")
+		}
+	}()
+	NewOrderedMapString([]string{"foo"}, nil)
+}
+
+func TestOrderedMapString_String(t *testing.T) {
+	age := NewOrderedMapString([]string{"zoo"}, []string{"world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Set("foo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "OrderedMapString{[zoo:world foo:bar]}"
+	if a != exp {
+		t.Fatalf("OrderedMapString.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestOrderedMapString_MarshalJSON(t *testing.T) {
+	age := NewOrderedMapString([]string{"zoo"}, []string{"world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set("foo", `"bar"`)
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"zoo":"world","foo":"\"bar\""}`
+	if string(b) != exp {
+		t.Fatalf("OrderedMapString.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+
+	b, err = json.Marshal(&OrderedMapString{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "{}" {
+		t.Fatalf("OrderedMapString.MarshalJSON() = %s, wanted %s", string(b), "{}")
+	}
+}
+
+func TestOrderedMapString_UnmarshalJSON(t *testing.T) {
+	data := []struct {
+		title string
+		buf   string
+		exp   []string
+		isErr bool
+	}{
+		{
+			title: "document order",
+			buf:   `{"zoo":"1","foo":"2","bar":"3","foo":"4"}`,
+			exp:   []string{"hello", "zoo", "foo", "bar"},
+		},
+		{
+			title: "null",
+			buf:   `null`,
+			exp:   []string{"hello"},
+		},
+		{
+			title: "array",
+			buf:   `["foo"]`,
+			exp:   []string{"hello"},
+			isErr: true,
+		},
+		{
+			title: "not a string value",
+			buf:   `{"foo":1}`,
+			exp:   []string{"hello"},
+			isErr: true,
+		},
+		{
+			title: "trailing space",
+			buf:   `{"foo":"bar"} `,
+			exp:   []string{"hello", "foo"},
+		},
+		{
+			title: "trailing data",
+			buf:   `{"foo":"bar"} garbage`,
+			exp:   []string{"hello"},
+			isErr: true,
+		},
+		{
+			title: "trailing value",
+			buf:   `null {}`,
+			exp:   []string{"hello"},
+			isErr: true,
+		},
+	}
+	for _, d := range data {
+		d := d
+		t.Run(d.title, func(t *testing.T) {
+			age := NewOrderedMapString([]string{"hello"}, []string{"world"})
+			// call UnmarshalJSON directly because json.Unmarshal rejects trailing data by itself.
+			err := age.UnmarshalJSON([]byte(d.buf))
+			if d.isErr {
+				if err == nil {
+					t.Fatal("error must be returned")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if a := age.Keys(); !reflect.DeepEqual(a, d.exp) {
+				t.Fatalf("OrderedMapString.Keys() = %v, wanted %v", a, d.exp)
+			}
+		})
+	}
+}
+
+func TestOrderedMapString_Get(t *testing.T) {
+	age := NewOrderedMapString([]string{"foo"}, []string{"bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Get("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", "zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Get("foo"); a != "zoo" {
+		t.Fatalf("OrderedMapString.Get() = %s, wanted %s", a, "zoo")
+	}
+	if a, ok := age.GetOk("bar"); a != "" || ok {
+		t.Fatalf("OrderedMapString.GetOk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if !age.Has("foo") {
+		t.Fatalf("OrderedMapString.Has() = %t, wanted %t", false, true)
+	}
+	if a := age.Len(); a != 1 {
+		t.Fatalf("OrderedMapString.Len() = %d, wanted %d", a, 1)
+	}
+}
+
+func TestOrderedMapString_Set(t *testing.T) {
+	age := &OrderedMapString{}
+	age.Set("zoo", "1")
+	age.Set("foo", "2")
+	age.Set("zoo", "3")
+	exp := []string{"zoo", "foo"}
+	if a := age.Keys(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("OrderedMapString.Keys() = %v, wanted %v", a, exp)
+	}
+	if a := age.Get("zoo"); a != "3" {
+		t.Fatalf("OrderedMapString.Get() = %s, wanted %s", a, "3")
+	}
+}
+
+func TestOrderedMapString_Delete(t *testing.T) {
+	age := NewOrderedMapString([]string{"foo", "zoo", "hello"}, []string{"bar", "world", "world"})
+	age.Delete("foo")
+	if a := age.DeleteR("zoo"); a != "world" {
+		t.Fatalf("OrderedMapString.DeleteR() = %s, wanted %s", a, "world")
+	}
+	if a, ok := age.DeleteROk("hello"); a != "world" || !ok {
+		t.Fatalf("OrderedMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "world", true)
+	}
+	if a := age.order.Len(); a != 0 {
+		t.Fatalf("age.order.Len() = %d, wanted %d", a, 0)
+	}
+	age.Set("foo", "bar")
+	exp := []string{"foo"}
+	if a := age.Keys(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("OrderedMapString.Keys() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestOrderedMapString_SetDefault(t *testing.T) {
+	age := NewOrderedMapString([]string{"foo"}, []string{"bar"})
+	age.SetDefault("foo", "zoo")
+	age.SetDefault("hello", "world")
+	if a, ok := age.SetDefaultR("zoo", "world"); a != "world" || ok {
+		t.Fatalf(`age.SetDefaultR("zoo") = %s, %t, wanted %s, %t`, a, ok, "world", false)
+	}
+	if a, ok := age.SetDefaultR("foo", "world"); a != "bar" || !ok {
+		t.Fatalf(`age.SetDefaultR("foo") = %s, %t, wanted %s, %t`, a, ok, "bar", true)
+	}
+	exp := []string{"foo", "hello", "zoo"}
+	if a := age.Keys(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("OrderedMapString.Keys() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestOrderedMapString_SetFunc(t *testing.T) {
+	age := &OrderedMapString{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			age.SetFunc("foo", func(v string, ok bool) string {
+				return v + "!"
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Get("foo"); a != "!!" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "!!")
+	}
+}
+
+func TestOrderedMapString_SetFuncE(t *testing.T) {
+	age := NewOrderedMapString([]string{"state"}, []string{"draft"})
+	errTransition := errors.New("invalid transition")
+	publish := func(v string, ok bool) (string, error) {
+		if v != "draft" {
+			return "", errTransition
+		}
+		return "published", nil
+	}
+	if err := age.SetFuncE("state", publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE("state", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("OrderedMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if a := age.Get("state"); a != "published" {
+		t.Fatalf(`OrderedMapString.Get("state") = %s, wanted published`, a)
+	}
+	if err := age.SetFuncE("foo", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("OrderedMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if age.Has("foo") {
+		t.Fatal(`the key "foo" must not be added`)
+	}
+}
+
+func TestOrderedMapString_CompareAndSwap(t *testing.T) {
+	age := NewOrderedMapString([]string{"foo"}, []string{"bar"})
+	if age.CompareAndSwap("foo", "zoo", "world") {
+		t.Fatal("CompareAndSwap must fail if the value isn't equal to old")
+	}
+	if !age.CompareAndSwap("foo", "bar", "world") {
+		t.Fatal("CompareAndSwap must succeed if the value is equal to old")
+	}
+	if a, _ := age.Swap("foo", "hello"); a != "world" {
+		t.Fatalf(`age.Swap("foo") = %s, wanted %s`, a, "world")
+	}
+	if !age.CompareAndDelete("foo", "hello") {
+		t.Fatal("CompareAndDelete must succeed if the value is equal to old")
+	}
+}
+
+func TestOrderedMapString_MoveToFront(t *testing.T) {
+	age := NewOrderedMapString([]string{"a", "b", "c"}, []string{"1", "2", "3"})
+	if !age.MoveToFront("c") {
+		t.Fatal("MoveToFront must succeed if the map has the key")
+	}
+	if age.MoveToFront("d") {
+		t.Fatal("MoveToFront must fail if the map doesn't have the key")
+	}
+	exp := []string{"c", "a", "b"}
+	if a := age.Keys(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("OrderedMapString.Keys() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestOrderedMapString_MoveToBack(t *testing.T) {
+	age := NewOrderedMapString([]string{"a", "b", "c"}, []string{"1", "2", "3"})
+	if !age.MoveToBack("a") {
+		t.Fatal("MoveToBack must succeed if the map has the key")
+	}
+	if age.MoveToBack("d") {
+		t.Fatal("MoveToBack must fail if the map doesn't have the key")
+	}
+	exp := []string{"b", "c", "a"}
+	if a := age.Keys(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("OrderedMapString.Keys() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestOrderedMapString_Range(t *testing.T) {
+	age := NewOrderedMapString([]string{"c", "a", "b"}, []string{"1", "2", "3"})
+	keys := []string{}
+	age.Range(func(k, v string) {
+		keys = append(keys, k)
+		// Range doesn't hold the lock while the function is called.
+		age.Set("d", "4")
+	})
+	exp := []string{"c", "a", "b"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+	keys = []string{}
+	age.RangeB(func(k, v string) bool {
+		keys = append(keys, k)
+		return len(keys) < 2
+	})
+	exp = []string{"c", "a"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+}
+
+func TestOrderedMapString_Copy(t *testing.T) {
+	age := NewOrderedMapString([]string{"c", "a"}, []string{"1", "2"})
+	cp := &OrderedMapString{}
+	age.Copy(cp)
+	exp := []string{"c", "a"}
+	if a := cp.Keys(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("cp.Keys() = %v, wanted %v", a, exp)
+	}
+	data := map[string]string{}
+	age.CopyData(data)
+	if a := len(data); a != 2 {
+		t.Fatalf("len(data) = %d, wanted %d", a, 2)
+	}
+}
@@ -0,0 +1,206 @@
+package safe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCorruptFile is returned by LoadFile and Snapshot.Load when the file is broken.
+var ErrCorruptFile = errors.New("safe: the file is corrupt")
+
+// JSONContainer is a container which can be encoded and decoded as JSON.
+// All containers of this package except DurableMapString implement JSONContainer.
+// DurableMapString persists itself by its write-ahead log, so it can't be decoded from JSON and passed to NewSnapshot.
+type JSONContainer interface {
+	json.Marshaler
+	json.Unmarshaler
+}
+
+// fileEnvelope is the content of the file written by SaveFile.
+// Checksum is the SHA-256 of Data to detect a broken file.
+type fileEnvelope struct {
+	Checksum string          `json:"checksum"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// filePerm is the permission of the file written by SaveFile.
+const filePerm os.FileMode = 0o644
+
+// saveFile encodes the value as JSON and writes it to the file atomically.
+func saveFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	buf, err := json.Marshal(fileEnvelope{
+		Checksum: hex.EncodeToString(sum[:]),
+		Data:     data,
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf)
+}
+
+// loadFile reads the file written by saveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func loadFile(path string, v json.Unmarshaler) error {
+	data, err := readFileData(path)
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalJSON(data)
+}
+
+// readFileData reads the file written by saveFile and returns the verified data.
+func readFileData(path string) ([]byte, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var envelope fileEnvelope
+	if err := json.Unmarshal(buf, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptFile, path, err)
+	}
+	sum := sha256.Sum256(envelope.Data)
+	if envelope.Checksum != hex.EncodeToString(sum[:]) {
+		return nil, fmt.Errorf("%w: %s: checksum mismatch", ErrCorruptFile, path)
+	}
+	return envelope.Data, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it to path,
+// so the file at path is either the old content or the new content even if the process crashes.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if err := writeAndSync(f, data); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return err
+	}
+	if err := os.Chmod(tmp, filePerm); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+func writeAndSync(f *os.File, data []byte) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir flushes the directory entries of the files created or renamed in the directory.
+// Some platforms don't support syncing a directory, so the error is ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()  //nolint:errcheck
+	d.Close() //nolint:errcheck
+}
+
+// Snapshot saves named containers into one file and loads them from the file.
+// Each container is encoded with its own lock, so the containers aren't saved at the same instant.
+type Snapshot struct {
+	path       string
+	containers map[string]JSONContainer
+}
+
+// NewSnapshot creates a Snapshot which saves the containers to the file.
+// The keys of `containers` are the names in the file.
+func NewSnapshot(path string, containers map[string]JSONContainer) *Snapshot {
+	return &Snapshot{
+		path:       path,
+		containers: containers,
+	}
+}
+
+// Save writes all containers to the file atomically.
+func (s *Snapshot) Save() error {
+	data := make(map[string]json.RawMessage, len(s.containers))
+	for name, c := range s.containers {
+		b, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", name, err)
+		}
+		data[name] = b
+	}
+	return saveFile(s.path, data)
+}
+
+// Load reads the file and decodes the containers.
+// The names in the file which aren't in the snapshot are ignored,
+// and the containers which aren't in the file are kept as they are.
+// ErrCorruptFile is returned if the file is broken.
+func (s *Snapshot) Load() error {
+	buf, err := readFileData(s.path)
+	if err != nil {
+		return err
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &data); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrCorruptFile, s.path, err)
+	}
+	for name, c := range s.containers {
+		b, ok := data[name]
+		if !ok {
+			continue
+		}
+		if err := c.UnmarshalJSON(b); err != nil {
+			return fmt.Errorf("decode %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// defaultSnapshotInterval is the interval of Snapshot.Run when the given interval isn't positive.
+const defaultSnapshotInterval = time.Minute
+
+// Run saves the containers every interval until the context is done,
+// and saves them once more before returning, so the last changes aren't lost at shutdown.
+// If interval isn't positive, the containers are saved every minute.
+// The errors of the periodic saves are passed to onError if onError isn't nil,
+// and the error of the last save is returned.
+func (s *Snapshot) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return s.Save()
+		case <-ticker.C:
+			if err := s.Save(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveFile(t *testing.T) {
+	dir := t.TempDir()
+	flag := newTestBool(true)
+	count := newTestInt(10)
+	name := &String{value: "foo"}
+	point := &Value[testPoint]{value: testPoint{X: 1, Y: 2}}
+	ages := NewMap(map[string]int{"foo": 1})
+	names := NewMapString(map[string]string{"foo": "bar"})
+	list := NewSlice([]int{1, 2})
+	set := NewSet(1, 2)
+	sharded := newTestShardedMapString(map[string]string{"foo": "bar"})
+	cow := NewCopyOnWriteMapString(map[string]string{"foo": "bar"})
+	ordered := NewOrderedMapString([]string{"b", "a"}, []string{"1", "2"})
+	sorted := NewSortedMapString(map[string]string{"foo": "bar"})
+	trie := NewTrieMapString(map[string]string{"foo": "bar"})
+	data := []struct {
+		title  string
+		saved  interface{ SaveFile(path string) error }
+		loaded interface{ LoadFile(path string) error }
+		exp    string
+	}{
+		{"Bool", flag, &Bool{}, "Bool{true}"},
+		{"Int", count, &Int{}, "Int{10}"},
+		{"String", name, &String{}, "String{foo}"},
+		{"Value", point, &Value[testPoint]{}, "Value{{1 2}}"},
+		{"Map", ages, NewMap(map[string]int{}), "Map{map[foo:1]}"},
+		{"MapString", names, NewMapString(map[string]string{}), "MapString{map[foo:bar]}"},
+		{"Slice", list, NewSlice[int](nil), "Slice{[1 2]}"},
+		{"Set", set, NewSet[int](), "Set{[1 2]}"},
+		{"ShardedMapString", sharded, newTestShardedMapString(nil), "ShardedMapString{map[foo:bar]}"},
+		{"CopyOnWriteMapString", cow, NewCopyOnWriteMapString(nil), "CopyOnWriteMapString{map[foo:bar]}"},
+		{"OrderedMapString", ordered, NewOrderedMapString(nil, nil), "OrderedMapString{[b:1 a:2]}"},
+		{"SortedMapString", sorted, NewSortedMapString(nil), "SortedMapString{[foo:bar]}"},
+		{"TrieMapString", trie, NewTrieMapString(nil), "TrieMapString{[foo:bar]}"},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			path := filepath.Join(dir, d.title+".json")
+			if err := d.saved.SaveFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.loaded.LoadFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if a := d.loaded.(interface{ String() string }).String(); a != d.exp {
+				t.Fatalf("%s.LoadFile() = %s, wanted %s", d.title, a, d.exp)
+			}
+		})
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(data) {
+		t.Fatalf("the temporary files must be removed: %d files are found, wanted %d", len(files), len(data))
+	}
+}
+
+func TestLoadFile_corrupt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "names.json")
+	names := NewMapString(map[string]string{"foo": "bar"})
+	if err := names.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string][]byte{
+		"truncated": buf[:len(buf)/2],
+		"modified":  []byte(string(buf[:len(buf)-4]) + `x"}}`),
+		"empty":     {},
+	}
+	for title, b := range data {
+		t.Run(title, func(t *testing.T) {
+			if err := os.WriteFile(path, b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			loaded := NewMapString(map[string]string{})
+			if err := loaded.LoadFile(path); !errors.Is(err, ErrCorruptFile) {
+				t.Fatalf("MapString.LoadFile() = %v, wanted %v", err, ErrCorruptFile)
+			}
+			if a := loaded.Len(); a != 0 {
+				t.Fatalf("MapString.Len() = %d, wanted %d", a, 0)
+			}
+		})
+	}
+	if err := names.LoadFile(filepath.Join(dir, "not_found.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("MapString.LoadFile() = %v, wanted %v", err, os.ErrNotExist)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	count := newTestInt(10)
+	names := NewMapString(map[string]string{"foo": "bar"})
+	if err := NewSnapshot(path, map[string]JSONContainer{
+		"count": count,
+		"names": names,
+	}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	loadedCount := &Int{}
+	loadedNames := NewMapString(map[string]string{})
+	unknown := &String{value: "foo"}
+	if err := NewSnapshot(path, map[string]JSONContainer{
+		"count":   loadedCount,
+		"names":   loadedNames,
+		"unknown": unknown,
+	}).Load(); err != nil {
+		t.Fatal(err)
+	}
+	if a := loadedCount.Get(); a != 10 {
+		t.Fatalf("Int.Get() = %d, wanted %d", a, 10)
+	}
+	if a := loadedNames.Get("foo"); a != "bar" {
+		t.Fatalf(`MapString.Get("foo") = %s, wanted bar`, a)
+	}
+	if a := unknown.Get(); a != "foo" {
+		t.Fatalf(`String.Get() = %s, wanted foo`, a)
+	}
+}
+
+func TestSnapshot_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	count := &Int{}
+	snapshot := NewSnapshot(path, map[string]JSONContainer{"count": count})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- snapshot.Run(ctx, time.Millisecond, func(err error) {
+			t.Error(err)
+		})
+	}()
+	count.Set(1)
+	loaded := &Int{}
+	restore := NewSnapshot(path, map[string]JSONContainer{"count": loaded})
+	for restore.Load() != nil || loaded.Get() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	count.Set(2)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// the last change is saved when the context is done.
+	if err := restore.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if a := loaded.Get(); a != 2 {
+		t.Fatalf("Int.Get() = %d, wanted %d", a, 2)
+	}
+}
+
+func TestSnapshot_Run_interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	count := newTestInt(1)
+	snapshot := NewSnapshot(path, map[string]JSONContainer{"count": count})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Run must not panic if the interval isn't positive.
+	if err := snapshot.Run(ctx, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &Int{}
+	if err := NewSnapshot(path, map[string]JSONContainer{"count": loaded}).Load(); err != nil {
+		t.Fatal(err)
+	}
+	if a := loaded.Get(); a != 1 {
+		t.Fatalf("Int.Get() = %d, wanted %d", a, 1)
+	}
+}
@@ -0,0 +1,69 @@
+package safe
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error returned by Recover when the function panics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine when the panic is recovered.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("safe: recovered from panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Recover calls the function and returns a panic in the function as *PanicError.
+// SetFuncE of each container returns a panic in its callback as *PanicError without Recover.
+// All methods which take a callback release the lock when the callback panics,
+// so the container can still be used after Recover returns an error.
+// For example,
+//
+//	err := safe.Recover(func() {
+//		m.SetFunc("count", increment)
+//	})
+func Recover(f func()) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	f()
+	return nil
+}
+
+// recoverFunc wraps the function of SetFuncE so that a panic in the function is returned as *PanicError.
+func recoverFunc[T any](f func(T) (T, error)) func(T) (T, error) {
+	return func(v T) (a T, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = &PanicError{Value: p, Stack: debug.Stack()}
+			}
+		}()
+		return f(v)
+	}
+}
+
+// recoverMapFunc is recoverFunc for the function of SetFuncE of maps.
+func recoverMapFunc[T any](f func(T, bool) (T, error)) func(T, bool) (T, error) {
+	return func(v T, ok bool) (a T, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = &PanicError{Value: p, Stack: debug.Stack()}
+			}
+		}()
+		return f(v, ok)
+	}
+}
@@ -0,0 +1,212 @@
+package safe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	if err := Recover(func() {}); err != nil {
+		t.Fatal(err)
+	}
+	errFoo := errors.New("foo")
+	err := Recover(func() {
+		panic(errFoo)
+	})
+	var pErr *PanicError
+	if !errors.As(err, &pErr) {
+		t.Fatalf("Recover() = %v, wanted *PanicError", err)
+	}
+	if !errors.Is(err, errFoo) {
+		t.Fatalf("Recover() = %v, wanted %v", err, errFoo)
+	}
+	if len(pErr.Stack) == 0 {
+		t.Fatal("PanicError.Stack is empty")
+	}
+}
+
+func TestRecover_callbacks(t *testing.T) {
+	b := &Bool{}
+	i := &Int{}
+	s := &String{}
+	val := &Value[int]{}
+	ms := NewMapString(map[string]string{})
+	sharded := NewShardedMapString(2)
+	cow := NewCopyOnWriteMapString(nil)
+	ordered := NewOrderedMapString(nil, nil)
+	sorted := NewSortedMapString(nil)
+	trie := NewTrieMapString(nil)
+	data := []struct {
+		title string
+		call  func()
+		after func()
+	}{
+		{
+			title: "Bool.SetFunc",
+			call:  func() { b.SetFunc(func(bool) bool { panic("foo") }) },
+			after: func() { b.Set(true) },
+		},
+		{
+			title: "Bool.Lock",
+			call:  func() { b.Lock(func(*BoolLocked) { panic("foo") }) },
+			after: func() { b.Set(true) },
+		},
+		{
+			title: "Int.SetFunc",
+			call:  func() { i.SetFunc(func(int) int { panic("foo") }) },
+			after: func() { i.Set(1) },
+		},
+		{
+			title: "Int.RLock",
+			call:  func() { i.RLock(func(*IntReadLocked) { panic("foo") }) },
+			after: func() { i.Set(1) },
+		},
+		{
+			title: "String.SetFunc",
+			call:  func() { s.SetFunc(func(string) string { panic("foo") }) },
+			after: func() { s.Set("foo") },
+		},
+		{
+			title: "Value.SetFunc",
+			call:  func() { val.SetFunc(func(int) int { panic("foo") }) },
+			after: func() { val.Set(1) },
+		},
+		{
+			title: "MapString.SetFunc",
+			call:  func() { ms.SetFunc("foo", func(string, bool) string { panic("foo") }) },
+			after: func() { ms.Set("foo", "bar") },
+		},
+		{
+			title: "MapString.Lock",
+			call:  func() { ms.Lock(func(*MapStringLocked) { panic("foo") }) },
+			after: func() { ms.Set("foo", "bar") },
+		},
+		{
+			title: "MapString.Update",
+			call:  func() { _ = ms.Update(func(*MapStringTx) error { panic("foo") }) },
+			after: func() { ms.Set("foo", "bar") },
+		},
+		{
+			title: "ShardedMapString.SetFunc",
+			call:  func() { sharded.SetFunc("foo", func(string, bool) string { panic("foo") }) },
+			after: func() { sharded.Set("foo", "bar") },
+		},
+		{
+			title: "CopyOnWriteMapString.SetFunc",
+			call:  func() { cow.SetFunc("foo", func(string, bool) string { panic("foo") }) },
+			after: func() { cow.Set("foo", "bar") },
+		},
+		{
+			title: "OrderedMapString.SetFunc",
+			call:  func() { ordered.SetFunc("foo", func(string, bool) string { panic("foo") }) },
+			after: func() { ordered.Set("foo", "bar") },
+		},
+		{
+			title: "SortedMapString.SetFunc",
+			call:  func() { sorted.SetFunc("foo", func(string, bool) string { panic("foo") }) },
+			after: func() { sorted.Set("foo", "bar") },
+		},
+		{
+			title: "TrieMapString.SetFunc",
+			call:  func() { trie.SetFunc("foo", func(string, bool) string { panic("foo") }) },
+			after: func() { trie.Set("foo", "bar") },
+		},
+		{
+			title: "Atomically",
+			call:  func() { Atomically(func() { panic("foo") }, val, s, ms) },
+			after: func() { ms.Set("foo", "bar") },
+		},
+	}
+	for _, d := range data {
+		d := d
+		t.Run(d.title, func(t *testing.T) {
+			var pErr *PanicError
+			if err := Recover(d.call); !errors.As(err, &pErr) || pErr.Value != "foo" {
+				t.Fatalf("Recover() = %v, wanted the panic foo", err)
+			}
+			// the lock must be released.
+			d.after()
+		})
+	}
+}
+
+func TestSetFuncE_panic(t *testing.T) {
+	b := &Bool{}
+	i := &Int{}
+	s := &String{}
+	val := &Value[int]{}
+	ms := NewMapString(map[string]string{})
+	sharded := NewShardedMapString(2)
+	cow := NewCopyOnWriteMapString(nil)
+	ordered := NewOrderedMapString(nil, nil)
+	sorted := NewSortedMapString(nil)
+	trie := NewTrieMapString(nil)
+	panicMap := func(string, bool) (string, error) { panic("foo") }
+	data := []struct {
+		title string
+		call  func() error
+		after func()
+	}{
+		{
+			title: "Bool.SetFuncE",
+			call:  func() error { return b.SetFuncE(func(bool) (bool, error) { panic("foo") }) },
+			after: func() { b.Set(true) },
+		},
+		{
+			title: "Int.SetFuncE",
+			call:  func() error { return i.SetFuncE(func(int) (int, error) { panic("foo") }) },
+			after: func() { i.Set(1) },
+		},
+		{
+			title: "String.SetFuncE",
+			call:  func() error { return s.SetFuncE(func(string) (string, error) { panic("foo") }) },
+			after: func() { s.Set("bar") },
+		},
+		{
+			title: "Value.SetFuncE",
+			call:  func() error { return val.SetFuncE(func(int) (int, error) { panic("foo") }) },
+			after: func() { val.Set(1) },
+		},
+		{
+			title: "MapString.SetFuncE",
+			call:  func() error { return ms.SetFuncE("foo", panicMap) },
+			after: func() { ms.Set("foo", "bar") },
+		},
+		{
+			title: "ShardedMapString.SetFuncE",
+			call:  func() error { return sharded.SetFuncE("foo", panicMap) },
+			after: func() { sharded.Set("foo", "bar") },
+		},
+		{
+			title: "CopyOnWriteMapString.SetFuncE",
+			call:  func() error { return cow.SetFuncE("foo", panicMap) },
+			after: func() { cow.Set("foo", "bar") },
+		},
+		{
+			title: "OrderedMapString.SetFuncE",
+			call:  func() error { return ordered.SetFuncE("foo", panicMap) },
+			after: func() { ordered.Set("foo", "bar") },
+		},
+		{
+			title: "SortedMapString.SetFuncE",
+			call:  func() error { return sorted.SetFuncE("foo", panicMap) },
+			after: func() { sorted.Set("foo", "bar") },
+		},
+		{
+			title: "TrieMapString.SetFuncE",
+			call:  func() error { return trie.SetFuncE("foo", panicMap) },
+			after: func() { trie.Set("foo", "bar") },
+		},
+	}
+	for _, d := range data {
+		d := d
+		t.Run(d.title, func(t *testing.T) {
+			var pErr *PanicError
+			if err := d.call(); !errors.As(err, &pErr) || pErr.Value != "foo" {
+				t.Fatalf("SetFuncE() = %v, wanted the panic foo", err)
+			}
+			// the lock must be released.
+			d.after()
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package safe
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Set is a set of comparable values.
+// Set must be used as the pointer because Set has sync.RWMutex as a private field.
+// A RWMutex must not be copied after first use.
+// https://golang.org/pkg/sync/#RWMutex
+//
+// The zero value is an empty Set.
+type Set[T comparable] struct {
+	value map[T]struct{}
+	mutex rwMutex
+}
+
+// NewSet creates a Set which has given values.
+func NewSet[T comparable](v ...T) *Set[T] {
+	s := &Set[T]{ // escapes to heap
+		value: make(map[T]struct{}, len(v)),
+	}
+	s.AddUnsafe(v...)
+	return s
+}
+
+func (s *Set[T]) String() string {
+	s.mutex.RLock()
+	v := s.sortedUnsafe()
+	s.mutex.RUnlock()
+	return "Set{" + fmt.Sprintf("%v", v) + "}"
+}
+
+// MarshalJSON encodes the set as a sorted array.
+// Numbers and strings are sorted by their values and other types are sorted by their string representations.
+func (s *Set[T]) MarshalJSON() ([]byte, error) {
+	s.mutex.RLock()
+	v := s.sortedUnsafe()
+	s.mutex.RUnlock()
+	if v == nil {
+		v = []T{}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes an array and replaces the values of the set.
+func (s *Set[T]) UnmarshalJSON(buf []byte) error {
+	var v []T
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	s.value = make(map[T]struct{}, len(v))
+	s.AddUnsafe(v...)
+	s.mutex.Unlock()
+	return nil
+}
+
+// Add adds values to the set with lock.
+func (s *Set[T]) Add(v ...T) {
+	s.mutex.Lock()
+	s.AddUnsafe(v...)
+	s.mutex.Unlock()
+}
+
+// Remove removes values from the set with lock.
+func (s *Set[T]) Remove(v ...T) {
+	s.mutex.Lock()
+	s.RemoveUnsafe(v...)
+	s.mutex.Unlock()
+}
+
+// Contains checks whether the set has the value with lock.
+func (s *Set[T]) Contains(v T) bool {
+	s.mutex.RLock()
+	_, ok := s.value[v]
+	s.mutex.RUnlock()
+	return ok
+}
+
+// Len gets the number of values in the set with lock.
+func (s *Set[T]) Len() int {
+	s.mutex.RLock()
+	v := len(s.value)
+	s.mutex.RUnlock()
+	return v
+}
+
+// Range gets all values from the set with lock and calls the function.
+func (s *Set[T]) Range(f func(v T)) {
+	s.mutex.RLock()
+	copied := make([]T, 0, len(s.value))
+	for v := range s.value {
+		copied = append(copied, v)
+	}
+	s.mutex.RUnlock()
+	for _, v := range copied {
+		f(v)
+	}
+}
+
+// RangeB gets all values from the set with lock and calls the function.
+// If the function returns false, the loop ends.
+func (s *Set[T]) RangeB(f func(v T) bool) {
+	s.mutex.RLock()
+	copied := make([]T, 0, len(s.value))
+	for v := range s.value {
+		copied = append(copied, v)
+	}
+	s.mutex.RUnlock()
+	for _, v := range copied {
+		if !f(v) {
+			break
+		}
+	}
+}
+
+// Union creates a new Set which has values in either s or other.
+// Both sets are locked in a fixed order, so calling a.Union(b) and b.Union(a) in parallel doesn't cause a deadlock.
+func (s *Set[T]) Union(other *Set[T]) *Set[T] {
+	unlock := rlockPair(&s.mutex, &other.mutex)
+	defer unlock()
+	ret := make(map[T]struct{}, len(s.value)+len(other.value))
+	for v := range s.value {
+		ret[v] = struct{}{}
+	}
+	for v := range other.value {
+		ret[v] = struct{}{}
+	}
+	return &Set[T]{value: ret}
+}
+
+// Intersect creates a new Set which has values in both s and other.
+// Both sets are locked in a fixed order, so calling a.Intersect(b) and b.Intersect(a) in parallel doesn't cause a deadlock.
+func (s *Set[T]) Intersect(other *Set[T]) *Set[T] {
+	unlock := rlockPair(&s.mutex, &other.mutex)
+	defer unlock()
+	small, large := s.value, other.value
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	ret := make(map[T]struct{}, len(small))
+	for v := range small {
+		if _, ok := large[v]; ok {
+			ret[v] = struct{}{}
+		}
+	}
+	return &Set[T]{value: ret}
+}
+
+// Difference creates a new Set which has values in s but not in other.
+// Both sets are locked in a fixed order, so calling a.Difference(b) and b.Difference(a) in parallel doesn't cause a deadlock.
+func (s *Set[T]) Difference(other *Set[T]) *Set[T] {
+	unlock := rlockPair(&s.mutex, &other.mutex)
+	defer unlock()
+	ret := make(map[T]struct{}, len(s.value))
+	for v := range s.value {
+		if _, ok := other.value[v]; !ok {
+			ret[v] = struct{}{}
+		}
+	}
+	return &Set[T]{value: ret}
+}
+
+// IsSubset checks whether all values in s are in other.
+// Both sets are locked in a fixed order, so calling a.IsSubset(b) and b.IsSubset(a) in parallel doesn't cause a deadlock.
+func (s *Set[T]) IsSubset(other *Set[T]) bool {
+	unlock := rlockPair(&s.mutex, &other.mutex)
+	defer unlock()
+	if len(s.value) > len(other.value) {
+		return false
+	}
+	for v := range s.value {
+		if _, ok := other.value[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Set[T]) sortedUnsafe() []T {
+	if len(s.value) == 0 {
+		return nil
+	}
+	v := make([]T, 0, len(s.value))
+	for a := range s.value {
+		v = append(v, a)
+	}
+	sortValues(v)
+	return v
+}
+
+func (s *Set[T]) mutexes() []*rwMutex {
+	return []*rwMutex{&s.mutex}
+}
+
+// SaveFile encodes the Set by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (s *Set[T]) SaveFile(path string) error {
+	return saveFile(path, s)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (s *Set[T]) LoadFile(path string) error {
+	return loadFile(path, s)
+}
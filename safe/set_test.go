@@ -0,0 +1,297 @@
+package safe
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestSet_String(t *testing.T) {
+	age := NewSet(3, 1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Add(2)
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "Set{[1 2 3]}"
+	if a != exp {
+		t.Fatalf("Set.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestSet_MarshalJSON(t *testing.T) {
+	data := []struct {
+		title string
+		set   *Set[int]
+		exp   string
+	}{
+		{
+			title: "sorted numerically",
+			set:   NewSet(10, 9, 100),
+			exp:   `[9,10,100]`,
+		},
+		{
+			title: "zero value",
+			set:   &Set[int]{},
+			exp:   `[]`,
+		},
+	}
+	for _, d := range data {
+		d := d
+		t.Run(d.title, func(t *testing.T) {
+			b, err := json.Marshal(d.set)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != d.exp {
+				t.Fatalf("Set.MarshalJSON() = %s, wanted %s", string(b), d.exp)
+			}
+		})
+	}
+}
+
+func TestSet_MarshalJSON_string(t *testing.T) {
+	age := NewSet("zoo", "foo")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Add("bar")
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `["bar","foo","zoo"]`
+	if string(b) != exp {
+		t.Fatalf("Set.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestSet_UnmarshalJSON(t *testing.T) {
+	age := NewSet("foo")
+	var wg sync.WaitGroup
+	buf := []byte(`["hello","world","hello"]`)
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Add("bar")
+		wg.Done()
+	}()
+	go func() {
+		err = json.Unmarshal(buf, age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf, age); err != nil {
+		t.Fatal(err)
+	}
+	if a := len(age.value); a != 2 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 2)
+	}
+	if err := json.Unmarshal([]byte(`{}`), age); err == nil {
+		t.Fatal("an object must be rejected")
+	}
+	if a := len(age.value); a != 2 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 2)
+	}
+}
+
+func TestSet_Add(t *testing.T) {
+	age := &Set[string]{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Add("foo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		age.Add("foo")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := len(age.value); a != 2 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 2)
+	}
+}
+
+func TestSet_Remove(t *testing.T) {
+	age := NewSet("foo", "bar", "zoo")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Remove("foo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		age.Remove("foo")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := len(age.value); a != 1 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 1)
+	}
+}
+
+func TestSet_Contains(t *testing.T) {
+	age := NewSet("foo")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Contains("bar")
+		wg.Done()
+	}()
+	go func() {
+		age.Add("bar")
+		wg.Done()
+	}()
+	wg.Wait()
+	if !age.Contains("bar") {
+		t.Fatalf("Set.Contains(bar) = %t, wanted %t", false, true)
+	}
+	if age.Contains("zoo") {
+		t.Fatalf("Set.Contains(zoo) = %t, wanted %t", true, false)
+	}
+}
+
+func TestSet_Len(t *testing.T) {
+	age := NewSet("foo")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Len()
+		wg.Done()
+	}()
+	go func() {
+		age.Add("bar")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Len(); a != 2 {
+		t.Fatalf("Set.Len() = %d, wanted %d", a, 2)
+	}
+}
+
+func TestSet_Range(t *testing.T) {
+	age := NewSet("foo")
+	age.Range(func(v string) {
+		if v != "foo" {
+			t.Fatalf("v = %s, wanted %s", v, "foo")
+		}
+		// Range doesn't hold the lock while the function is called.
+		age.Add("bar")
+	})
+}
+
+func TestSet_RangeB(t *testing.T) {
+	age := NewSet("foo", "bar")
+	cnt := 0
+	age.RangeB(func(v string) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
+
+func TestSet_Union(t *testing.T) {
+	a := NewSet(1, 2)
+	b := NewSet(2, 3)
+	if s := a.Union(b).String(); s != "Set{[1 2 3]}" {
+		t.Fatalf("a.Union(b) = %s, wanted %s", s, "Set{[1 2 3]}")
+	}
+	if s := a.Union(a).String(); s != "Set{[1 2]}" {
+		t.Fatalf("a.Union(a) = %s, wanted %s", s, "Set{[1 2]}")
+	}
+}
+
+func TestSet_Intersect(t *testing.T) {
+	a := NewSet(1, 2, 3)
+	b := NewSet(2, 3, 4)
+	if s := a.Intersect(b).String(); s != "Set{[2 3]}" {
+		t.Fatalf("a.Intersect(b) = %s, wanted %s", s, "Set{[2 3]}")
+	}
+	if s := a.Intersect(&Set[int]{}).String(); s != "Set{[]}" {
+		t.Fatalf("a.Intersect(empty) = %s, wanted %s", s, "Set{[]}")
+	}
+}
+
+func TestSet_Difference(t *testing.T) {
+	a := NewSet(1, 2, 3)
+	b := NewSet(2, 3, 4)
+	if s := a.Difference(b).String(); s != "Set{[1]}" {
+		t.Fatalf("a.Difference(b) = %s, wanted %s", s, "Set{[1]}")
+	}
+	if s := b.Difference(a).String(); s != "Set{[4]}" {
+		t.Fatalf("b.Difference(a) = %s, wanted %s", s, "Set{[4]}")
+	}
+}
+
+func TestSet_IsSubset(t *testing.T) {
+	a := NewSet(1, 2)
+	b := NewSet(1, 2, 3)
+	if !a.IsSubset(b) {
+		t.Fatal("a.IsSubset(b) must be true")
+	}
+	if b.IsSubset(a) {
+		t.Fatal("b.IsSubset(a) must be false")
+	}
+	if !a.IsSubset(a) {
+		t.Fatal("a.IsSubset(a) must be true")
+	}
+}
+
+func TestSet_Union_deadlock(t *testing.T) {
+	a := NewSet(1, 2)
+	b := NewSet(2, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(4)
+		go func() {
+			a.Union(b)
+			wg.Done()
+		}()
+		go func() {
+			b.Union(a)
+			wg.Done()
+		}()
+		go func() {
+			a.Add(4)
+			wg.Done()
+		}()
+		go func() {
+			b.Add(5)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkSet_Add(b *testing.B) {
+	age := &Set[int]{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		age.Add(i)
+	}
+}
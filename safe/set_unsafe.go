@@ -0,0 +1,46 @@
+package safe
+
+// AddUnsafe adds values to the set without lock.
+func (s *Set[T]) AddUnsafe(v ...T) {
+	if s.value == nil {
+		s.value = make(map[T]struct{}, len(v))
+	}
+	for _, a := range v {
+		s.value[a] = struct{}{}
+	}
+}
+
+// RemoveUnsafe removes values from the set without lock.
+func (s *Set[T]) RemoveUnsafe(v ...T) {
+	for _, a := range v {
+		delete(s.value, a)
+	}
+}
+
+// ContainsUnsafe checks whether the set has the value without lock.
+func (s *Set[T]) ContainsUnsafe(v T) bool {
+	_, ok := s.value[v]
+	return ok
+}
+
+// LenUnsafe gets the number of values in the set without lock.
+func (s *Set[T]) LenUnsafe() int {
+	return len(s.value)
+}
+
+// RangeUnsafe gets all values from the set and calls the function without lock.
+func (s *Set[T]) RangeUnsafe(f func(v T)) {
+	for v := range s.value {
+		f(v)
+	}
+}
+
+// RangeBUnsafe gets values from the set and calls the function without lock.
+// If the function returns false, the loop ends.
+func (s *Set[T]) RangeBUnsafe(f func(v T) bool) {
+	for v := range s.value {
+		if !f(v) {
+			break
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package safe
+
+import (
+	"testing"
+)
+
+func TestSet_AddUnsafe(t *testing.T) {
+	age := &Set[string]{}
+	age.AddUnsafe("foo", "bar", "foo")
+	if a := len(age.value); a != 2 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 2)
+	}
+}
+
+func TestSet_RemoveUnsafe(t *testing.T) {
+	age := NewSet("foo", "bar")
+	age.RemoveUnsafe("foo")
+	if a := len(age.value); a != 1 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 1)
+	}
+}
+
+func TestSet_ContainsUnsafe(t *testing.T) {
+	age := NewSet("foo")
+	if !age.ContainsUnsafe("foo") {
+		t.Fatalf("Set.ContainsUnsafe(foo) = %t, wanted %t", false, true)
+	}
+}
+
+func TestSet_LenUnsafe(t *testing.T) {
+	age := NewSet("foo")
+	if a := age.LenUnsafe(); a != 1 {
+		t.Fatalf("Set.LenUnsafe() = %d, wanted %d", a, 1)
+	}
+}
+
+func TestSet_RangeUnsafe(t *testing.T) {
+	age := NewSet("foo")
+	age.RangeUnsafe(func(v string) {
+		if v != "foo" {
+			t.Fatalf("v = %s, wanted %s", v, "foo")
+		}
+	})
+}
+
+func TestSet_RangeBUnsafe(t *testing.T) {
+	age := NewSet("foo", "bar")
+	cnt := 0
+	age.RangeBUnsafe(func(v string) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
@@ -0,0 +1,238 @@
+package safe
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/maphash"
+)
+
+// ShardedMapString is a MapString whose keys are distributed to some shards by their hash.
+// Each shard has its own lock, so operations for keys in different shards don't block each other.
+// ShardedMapString must be created by NewShardedMapString.
+//
+// Len, Range, RangeB, Copy, CopyData, String and MarshalJSON lock all shards at once,
+// so they get a consistent view over all shards.
+// ShardedMapString doesn't provide the Unsafe methods.
+type ShardedMapString struct {
+	shards []Map[string, string]
+	seed   maphash.Seed
+}
+
+// NewShardedMapString creates a ShardedMapString which has the given number of shards.
+// If shards is less than 1, 1 is used.
+func NewShardedMapString(shards int) *ShardedMapString {
+	if shards < 1 {
+		shards = 1
+	}
+	m := &ShardedMapString{
+		shards: make([]Map[string, string], shards),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range m.shards {
+		m.shards[i].value = map[string]string{}
+	}
+	return m
+}
+
+func (m *ShardedMapString) shard(k string) *Map[string, string] {
+	return &m.shards[maphash.String(m.seed, k)%uint64(len(m.shards))]
+}
+
+func (m *ShardedMapString) rlockAll() {
+	for i := range m.shards {
+		m.shards[i].mutex.RLock()
+	}
+}
+
+func (m *ShardedMapString) runlockAll() {
+	for i := range m.shards {
+		m.shards[i].mutex.RUnlock()
+	}
+}
+
+func (m *ShardedMapString) lockAll() {
+	for i := range m.shards {
+		m.shards[i].mutex.Lock()
+	}
+}
+
+func (m *ShardedMapString) unlockAll() {
+	for i := range m.shards {
+		m.shards[i].mutex.Unlock()
+	}
+}
+
+// snapshot copies all pairs of the key and value with locking all shards.
+func (m *ShardedMapString) snapshot() map[string]string {
+	m.rlockAll()
+	n := 0
+	for i := range m.shards {
+		n += len(m.shards[i].value)
+	}
+	copiedM := make(map[string]string, n)
+	for i := range m.shards {
+		for k, v := range m.shards[i].value {
+			copiedM[k] = v
+		}
+	}
+	m.runlockAll()
+	return copiedM
+}
+
+func (m *ShardedMapString) String() string {
+	return "ShardedMapString{" + fmt.Sprintf("%v", m.snapshot()) + "}"
+}
+
+func (m *ShardedMapString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.snapshot())
+}
+
+func (m *ShardedMapString) UnmarshalJSON(buf []byte) error {
+	var v map[string]string
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	m.lockAll()
+	for k, a := range v {
+		m.shard(k).value[k] = a
+	}
+	m.unlockAll()
+	return nil
+}
+
+// Get gets a value from the map with lock.
+func (m *ShardedMapString) Get(k string) string {
+	return m.shard(k).Get(k)
+}
+
+// GetOk gets a value from the map with lock.
+func (m *ShardedMapString) GetOk(k string) (string, bool) {
+	return m.shard(k).GetOk(k)
+}
+
+// Has checks whether the map has the key with lock.
+func (m *ShardedMapString) Has(k string) bool {
+	return m.shard(k).Has(k)
+}
+
+// Len gets the length of the map with locking all shards.
+func (m *ShardedMapString) Len() int {
+	m.rlockAll()
+	n := 0
+	for i := range m.shards {
+		n += len(m.shards[i].value)
+	}
+	m.runlockAll()
+	return n
+}
+
+// Delete deletes the key from the map with lock.
+func (m *ShardedMapString) Delete(k string) {
+	m.shard(k).Delete(k)
+}
+
+// DeleteR deletes the key from the map and returns the value with lock.
+func (m *ShardedMapString) DeleteR(k string) string {
+	return m.shard(k).DeleteR(k)
+}
+
+// DeleteROk deletes the key from the map and returns the value with lock.
+func (m *ShardedMapString) DeleteROk(k string) (string, bool) {
+	return m.shard(k).DeleteROk(k)
+}
+
+// Set sets the key and value to the map with lock.
+func (m *ShardedMapString) Set(k, v string) {
+	m.shard(k).Set(k, v)
+}
+
+// SetDefault sets the key and value to the map if the map doesn't have the key with lock.
+func (m *ShardedMapString) SetDefault(k, v string) {
+	m.shard(k).SetDefault(k, v)
+}
+
+// SetDefaultR sets the key and value to the map if the map doesn't have the key and returns the value with lock.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (m *ShardedMapString) SetDefaultR(k, v string) (string, bool) {
+	return m.shard(k).SetDefaultR(k, v)
+}
+
+// SetFunc gets a value of the key from the map and calls the function and sets the returned value to the map with lock.
+// This is used to update the value based on the original value atomicaly.
+func (m *ShardedMapString) SetFunc(k string, f func(string, bool) string) {
+	m.shard(k).SetFunc(k, f)
+}
+
+// SetFuncE is like SetFunc but the map isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the map isn't updated and the panic is returned as *PanicError.
+func (m *ShardedMapString) SetFuncE(k string, f func(string, bool) (string, error)) error {
+	f = recoverMapFunc(f)
+	return m.shard(k).SetFuncE(k, f)
+}
+
+// Swap sets the key and value to the map and returns the previous value with lock.
+// true is returned if the map has had the key.
+func (m *ShardedMapString) Swap(k, v string) (string, bool) {
+	return m.shard(k).Swap(k, v)
+}
+
+// CompareAndSwap sets the value of the key only if the map has the key and the current value is equal to old with lock.
+// true is returned if the value is updated.
+func (m *ShardedMapString) CompareAndSwap(k, old, v string) bool {
+	return m.shard(k).CompareAndSwap(k, old, v)
+}
+
+// CompareAndDelete deletes the key only if the current value is equal to old with lock.
+// true is returned if the key is deleted.
+func (m *ShardedMapString) CompareAndDelete(k, old string) bool {
+	return m.shard(k).CompareAndDelete(k, old)
+}
+
+// Range gets all pairs of the key and value from the map with locking all shards and calls the function.
+func (m *ShardedMapString) Range(f func(k, v string)) {
+	for k, v := range m.snapshot() {
+		f(k, v)
+	}
+}
+
+// RangeB gets all pairs of the key and value from the map with locking all shards and calls the function.
+// If the function returns false, the loop ends.
+func (m *ShardedMapString) RangeB(f func(k, v string) bool) {
+	for k, v := range m.snapshot() {
+		if !f(k, v) {
+			break
+		}
+	}
+}
+
+// Copy copies all pairs of the key and value to target.
+// The pairs are copied from a consistent view of m, but target isn't locked as a whole.
+func (m *ShardedMapString) Copy(target *ShardedMapString) {
+	for k, v := range m.snapshot() {
+		target.Set(k, v)
+	}
+}
+
+// CopyData copies all pairs of the key and value to target.
+func (m *ShardedMapString) CopyData(target map[string]string) {
+	m.rlockAll()
+	for i := range m.shards {
+		for k, v := range m.shards[i].value {
+			target[k] = v
+		}
+	}
+	m.runlockAll()
+}
+
+// SaveFile encodes the ShardedMapString by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (m *ShardedMapString) SaveFile(path string) error {
+	return saveFile(path, m)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (m *ShardedMapString) LoadFile(path string) error {
+	return loadFile(path, m)
+}
@@ -0,0 +1,299 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestShardedMapString(value map[string]string) *ShardedMapString {
+	m := NewShardedMapString(4)
+	for k, v := range value {
+		m.Set(k, v)
+	}
+	return m
+}
+
+func TestNewShardedMapString(t *testing.T) {
+	if a := len(NewShardedMapString(0).shards); a != 1 {
+		t.Fatalf("len(shards) = %d, wanted %d", a, 1)
+	}
+	age := NewShardedMapString(8)
+	for i := 0; i < 100; i++ {
+		age.Set(strconv.Itoa(i), "")
+	}
+	used := 0
+	for i := range age.shards {
+		if len(age.shards[i].value) != 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Fatalf("keys must be distributed to shards, but only %d shard is used", used)
+	}
+}
+
+func TestShardedMapString_String(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Set("zoo", "world")
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "ShardedMapString{map[foo:bar zoo:world]}"
+	if a != exp {
+		t.Fatalf("ShardedMapString.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestShardedMapString_MarshalJSON(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set("zoo", "world")
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"foo":"bar","zoo":"world"}`
+	if string(b) != exp {
+		t.Fatalf("ShardedMapString.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestShardedMapString_UnmarshalJSON(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	buf := []byte(`{"hello":"world","zoo":"world"}`)
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set("zoo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		err = json.Unmarshal(buf, age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf, age); err != nil {
+		t.Fatal(err)
+	}
+	if a := age.Len(); a != 3 {
+		t.Fatalf("ShardedMapString.Len() = %d, wanted %d", a, 3)
+	}
+	if a := age.Get("hello"); a != "world" {
+		t.Fatalf(`ShardedMapString.Get("hello") = %s, wanted %s`, a, "world")
+	}
+}
+
+func TestShardedMapString_Get(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Get("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", "zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Get("foo"); a != "zoo" {
+		t.Fatalf("ShardedMapString.Get() = %s, wanted %s", a, "zoo")
+	}
+	if a, ok := age.GetOk("bar"); a != "" || ok {
+		t.Fatalf("ShardedMapString.GetOk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if !age.Has("foo") {
+		t.Fatalf("ShardedMapString.Has() = %t, wanted %t", false, true)
+	}
+}
+
+func TestShardedMapString_Len(t *testing.T) {
+	age := NewShardedMapString(4)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(2)
+		go func() {
+			age.Set(strconv.Itoa(i), "")
+			wg.Done()
+		}()
+		go func() {
+			age.Len()
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Len(); a != 10 {
+		t.Fatalf("ShardedMapString.Len() = %d, wanted %d", a, 10)
+	}
+}
+
+func TestShardedMapString_Delete(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar", "zoo": "world", "hello": "world"})
+	age.Delete("foo")
+	if a := age.DeleteR("zoo"); a != "world" {
+		t.Fatalf("ShardedMapString.DeleteR() = %s, wanted %s", a, "world")
+	}
+	if a, ok := age.DeleteROk("hello"); a != "world" || !ok {
+		t.Fatalf("ShardedMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "world", true)
+	}
+	if a := age.Len(); a != 0 {
+		t.Fatalf("ShardedMapString.Len() = %d, wanted %d", a, 0)
+	}
+}
+
+func TestShardedMapString_SetDefault(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar"})
+	age.SetDefault("foo", "zoo")
+	if a := age.Get("foo"); a != "bar" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "bar")
+	}
+	if a, ok := age.SetDefaultR("zoo", "world"); a != "world" || ok {
+		t.Fatalf(`age.SetDefaultR("zoo") = %s, %t, wanted %s, %t`, a, ok, "world", false)
+	}
+}
+
+func TestShardedMapString_SetFunc(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			age.SetFunc("foo", func(v string, ok bool) string {
+				return v + "!"
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Get("foo"); a != "bar!!" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "bar!!")
+	}
+}
+
+func TestShardedMapString_SetFuncE(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"state": "draft"})
+	errTransition := errors.New("invalid transition")
+	publish := func(v string, ok bool) (string, error) {
+		if v != "draft" {
+			return "", errTransition
+		}
+		return "published", nil
+	}
+	if err := age.SetFuncE("state", publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE("state", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("ShardedMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if a := age.Get("state"); a != "published" {
+		t.Fatalf(`ShardedMapString.Get("state") = %s, wanted published`, a)
+	}
+	if err := age.SetFuncE("foo", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("ShardedMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if age.Has("foo") {
+		t.Fatal(`the key "foo" must not be added`)
+	}
+}
+
+func TestShardedMapString_CompareAndSwap(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar"})
+	if !age.CompareAndSwap("foo", "bar", "world") {
+		t.Fatal("CompareAndSwap must succeed if the value is equal to old")
+	}
+	if a, _ := age.Swap("foo", "hello"); a != "world" {
+		t.Fatalf(`age.Swap("foo") = %s, wanted %s`, a, "world")
+	}
+	if !age.CompareAndDelete("foo", "hello") {
+		t.Fatal("CompareAndDelete must succeed if the value is equal to old")
+	}
+}
+
+func TestShardedMapString_Range(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar", "zoo": "world"})
+	keys := []string{}
+	age.Range(func(k, v string) {
+		keys = append(keys, k)
+		// Range doesn't hold the lock while the function is called.
+		age.Set("hello", "world")
+	})
+	if len(keys) != 2 {
+		t.Fatalf("keys = %v, wanted 2 keys", keys)
+	}
+	cnt := 0
+	age.RangeB(func(k, v string) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
+
+func TestShardedMapString_Copy(t *testing.T) {
+	age := newTestShardedMapString(map[string]string{"foo": "bar", "zoo": "world"})
+	cp := NewShardedMapString(2)
+	age.Copy(cp)
+	if a := cp.Len(); a != 2 {
+		t.Fatalf("cp.Len() = %d, wanted %d", a, 2)
+	}
+	data := map[string]string{}
+	age.CopyData(data)
+	if a := strings.Join([]string{data["foo"], data["zoo"]}, ","); a != "bar,world" {
+		t.Fatalf("data = %v", data)
+	}
+}
+
+func BenchmarkShardedMapString_Set_parallel(b *testing.B) {
+	b.Run("sharded", func(b *testing.B) {
+		age := NewShardedMapString(32)
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				age.Set(strconv.Itoa(i%1000), "bar")
+				i++
+			}
+		})
+	})
+	b.Run("MapString", func(b *testing.B) {
+		age := NewMapString(map[string]string{})
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				age.Set(strconv.Itoa(i%1000), "bar")
+				i++
+			}
+		})
+	})
+}
@@ -0,0 +1,149 @@
+package safe
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Slice wraps []T.
+// Slice must be used as the pointer because Slice has sync.RWMutex as a private field.
+// A RWMutex must not be copied after first use.
+// https://golang.org/pkg/sync/#RWMutex
+//
+// The zero value is an empty Slice.
+// Like a builtin slice, the methods which take an index panic if the index is out of range.
+type Slice[T any] struct {
+	value []T
+	mutex rwMutex
+}
+
+// NewSlice creates a Slice.
+// Note that the argument `value` is holden in Slice, so don't read and write `value` out of the Slice.
+func NewSlice[T any](value []T) *Slice[T] {
+	return &Slice[T]{ // escapes to heap
+		value: value,
+	}
+}
+
+func (s *Slice[T]) String() string {
+	s.mutex.RLock()
+	v := "Slice{" + fmt.Sprintf("%v", s.value) + "}"
+	s.mutex.RUnlock()
+	return v
+}
+
+func (s *Slice[T]) MarshalJSON() ([]byte, error) {
+	s.mutex.RLock()
+	b, err := json.Marshal(s.value)
+	s.mutex.RUnlock()
+	return b, err
+}
+
+func (s *Slice[T]) UnmarshalJSON(buf []byte) error {
+	s.mutex.Lock()
+	err := json.Unmarshal(buf, &s.value)
+	s.mutex.Unlock()
+	return err
+}
+
+// Get gets the i-th element with lock.
+func (s *Slice[T]) Get(i int) T {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.value[i]
+}
+
+// Set sets the i-th element with lock.
+func (s *Slice[T]) Set(i int, v T) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.value[i] = v
+}
+
+// Len gets the length of the slice with lock.
+func (s *Slice[T]) Len() int {
+	s.mutex.RLock()
+	v := len(s.value)
+	s.mutex.RUnlock()
+	return v
+}
+
+// Append appends elements to the slice with lock.
+func (s *Slice[T]) Append(v ...T) {
+	s.mutex.Lock()
+	s.value = append(s.value, v...)
+	s.mutex.Unlock()
+}
+
+// Insert inserts elements at the index i with lock.
+// i may be equal to the length of the slice, then the elements are appended.
+func (s *Slice[T]) Insert(i int, v ...T) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.InsertUnsafe(i, v...)
+}
+
+// Delete deletes the i-th element with lock.
+func (s *Slice[T]) Delete(i int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.DeleteUnsafe(i)
+}
+
+// Truncate shortens the slice to the length n with lock.
+// If n is greater than or equal to the length of the slice, Truncate does nothing.
+func (s *Slice[T]) Truncate(n int) {
+	s.mutex.Lock()
+	s.TruncateUnsafe(n)
+	s.mutex.Unlock()
+}
+
+// Range gets all elements from the slice with lock and calls the function.
+func (s *Slice[T]) Range(f func(i int, v T)) {
+	s.mutex.RLock()
+	copied := make([]T, len(s.value))
+	copy(copied, s.value)
+	s.mutex.RUnlock()
+	for i, v := range copied {
+		f(i, v)
+	}
+}
+
+// RangeB gets all elements from the slice with lock and calls the function.
+// If the function returns false, the loop ends.
+func (s *Slice[T]) RangeB(f func(i int, v T) bool) {
+	s.mutex.RLock()
+	copied := make([]T, len(s.value))
+	copy(copied, s.value)
+	s.mutex.RUnlock()
+	for i, v := range copied {
+		if !f(i, v) {
+			break
+		}
+	}
+}
+
+// CopyData returns a copy of an internal slice.
+func (s *Slice[T]) CopyData() []T {
+	s.mutex.RLock()
+	copied := make([]T, len(s.value))
+	copy(copied, s.value)
+	s.mutex.RUnlock()
+	return copied
+}
+
+func (s *Slice[T]) mutexes() []*rwMutex {
+	return []*rwMutex{&s.mutex}
+}
+
+// SaveFile encodes the Slice by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (s *Slice[T]) SaveFile(path string) error {
+	return saveFile(path, s)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (s *Slice[T]) LoadFile(path string) error {
+	return loadFile(path, s)
+}
@@ -0,0 +1,317 @@
+package safe
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSlice_String(t *testing.T) {
+	age := &Slice[int]{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Append(1, 2)
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "Slice{[1 2]}"
+	if a != exp {
+		t.Fatalf("Slice.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestSlice_MarshalJSON(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Append("bar")
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `["foo","bar"]`
+	if string(b) != exp {
+		t.Fatalf("Slice.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestSlice_UnmarshalJSON(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	var wg sync.WaitGroup
+	buf := []byte(`["hello","world"]`)
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Append("bar")
+		wg.Done()
+	}()
+	go func() {
+		err = json.Unmarshal(buf, age)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf, age); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"hello", "world"}
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+}
+
+func TestSlice_Get(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Get(0)
+		wg.Done()
+	}()
+	go func() {
+		age.Set(0, "bar")
+		wg.Done()
+	}()
+	wg.Wait()
+	a := age.Get(0)
+	if a != "bar" {
+		t.Fatalf("Slice.Get(0) = %s, wanted %s", a, "bar")
+	}
+}
+
+func TestSlice_Get_outOfRange(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Slice.Get(1) must panic")
+			}
+		}()
+		age.Get(1)
+	}()
+	// the lock must be released even if Get panics.
+	age.Append("bar")
+}
+
+func TestSlice_Set(t *testing.T) {
+	age := NewSlice([]string{"foo", "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set(0, "zoo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set(1, "zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+	exp := []string{"zoo", "zoo"}
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+}
+
+func TestSlice_Len(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Len()
+		wg.Done()
+	}()
+	go func() {
+		age.Append("bar")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Len(); a != 2 {
+		t.Fatalf("Slice.Len() = %d, wanted %d", a, 2)
+	}
+}
+
+func TestSlice_Append(t *testing.T) {
+	age := &Slice[int]{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Append(1)
+		wg.Done()
+	}()
+	go func() {
+		age.Append(2, 3)
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := len(age.value); a != 3 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 3)
+	}
+}
+
+func TestSlice_Insert(t *testing.T) {
+	data := []struct {
+		title string
+		value []int
+		index int
+		args  []int
+		exp   []int
+	}{
+		{
+			title: "head",
+			value: []int{1, 2},
+			index: 0,
+			args:  []int{3, 4},
+			exp:   []int{3, 4, 1, 2},
+		},
+		{
+			title: "middle",
+			value: []int{1, 2},
+			index: 1,
+			args:  []int{3},
+			exp:   []int{1, 3, 2},
+		},
+		{
+			title: "tail",
+			value: []int{1, 2},
+			index: 2,
+			args:  []int{3},
+			exp:   []int{1, 2, 3},
+		},
+	}
+	for _, d := range data {
+		d := d
+		t.Run(d.title, func(t *testing.T) {
+			age := NewSlice(d.value)
+			age.Insert(d.index, d.args...)
+			if !reflect.DeepEqual(age.value, d.exp) {
+				t.Fatalf("age.value = %v, wanted %v", age.value, d.exp)
+			}
+		})
+	}
+}
+
+func TestSlice_Insert_outOfRange(t *testing.T) {
+	age := NewSlice(make([]int, 2, 10))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Slice.Insert(5) must panic")
+			}
+		}()
+		age.Insert(5, 9)
+	}()
+	// the slice must not be changed even if the index is less than the capacity.
+	exp := []int{0, 0}
+	if a := age.CopyData(); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("Slice.CopyData() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestSlice_Delete(t *testing.T) {
+	age := NewSlice([]int{1, 2, 3, 4})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Delete(0)
+		wg.Done()
+	}()
+	go func() {
+		age.Delete(0)
+		wg.Done()
+	}()
+	wg.Wait()
+	exp := []int{3, 4}
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+}
+
+func TestSlice_Truncate(t *testing.T) {
+	age := NewSlice([]int{1, 2, 3, 4})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Truncate(3)
+		wg.Done()
+	}()
+	go func() {
+		age.Truncate(2)
+		wg.Done()
+	}()
+	wg.Wait()
+	exp := []int{1, 2}
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+	age.Truncate(5)
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+}
+
+func TestSlice_Range(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	age.Range(func(i int, v string) {
+		if i != 0 {
+			t.Fatalf("i = %d, wanted %d", i, 0)
+		}
+		if v != "foo" {
+			t.Fatalf("v = %s, wanted %s", v, "foo")
+		}
+		// Range doesn't hold the lock while the function is called.
+		age.Append("bar")
+	})
+}
+
+func TestSlice_RangeB(t *testing.T) {
+	age := NewSlice([]string{"foo", "bar"})
+	cnt := 0
+	age.RangeB(func(i int, v string) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
+
+func TestSlice_CopyData(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	cp := age.CopyData()
+	cp[0] = "bar"
+	if age.value[0] != "foo" {
+		t.Fatalf("age.value[0] = %s, wanted %s", age.value[0], "foo")
+	}
+}
+
+func BenchmarkSlice_Append(b *testing.B) {
+	age := &Slice[int]{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		age.Append(i)
+	}
+}
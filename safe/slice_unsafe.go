@@ -0,0 +1,71 @@
+package safe
+
+// GetUnsafe gets the i-th element without lock.
+func (s *Slice[T]) GetUnsafe(i int) T {
+	return s.value[i]
+}
+
+// SetUnsafe sets the i-th element without lock.
+func (s *Slice[T]) SetUnsafe(i int, v T) {
+	s.value[i] = v
+}
+
+// LenUnsafe gets the length of the slice without lock.
+func (s *Slice[T]) LenUnsafe() int {
+	return len(s.value)
+}
+
+// AppendUnsafe appends elements to the slice without lock.
+func (s *Slice[T]) AppendUnsafe(v ...T) {
+	s.value = append(s.value, v...)
+}
+
+// InsertUnsafe inserts elements at the index i without lock.
+// i may be equal to the length of the slice, then the elements are appended.
+func (s *Slice[T]) InsertUnsafe(i int, v ...T) {
+	n := len(s.value)
+	_ = s.value[i:n] // panic if i is out of range before the slice is changed
+	s.value = append(s.value, v...)
+	copy(s.value[i+len(v):], s.value[i:n])
+	copy(s.value[i:], v)
+}
+
+// DeleteUnsafe deletes the i-th element without lock.
+func (s *Slice[T]) DeleteUnsafe(i int) {
+	_ = s.value[i] // panic if i is out of range
+	copy(s.value[i:], s.value[i+1:])
+	var zero T
+	// Clear the last element so that the garbage collector can free it.
+	s.value[len(s.value)-1] = zero
+	s.value = s.value[:len(s.value)-1]
+}
+
+// TruncateUnsafe shortens the slice to the length n without lock.
+// If n is greater than or equal to the length of the slice, TruncateUnsafe does nothing.
+func (s *Slice[T]) TruncateUnsafe(n int) {
+	if n >= len(s.value) {
+		return
+	}
+	var zero T
+	for i := n; i < len(s.value); i++ {
+		s.value[i] = zero
+	}
+	s.value = s.value[:n]
+}
+
+// RangeUnsafe gets all elements from the slice and calls the function without lock.
+func (s *Slice[T]) RangeUnsafe(f func(i int, v T)) {
+	for i, v := range s.value {
+		f(i, v)
+	}
+}
+
+// RangeBUnsafe gets elements from the slice and calls the function without lock.
+// If the function returns false, the loop ends.
+func (s *Slice[T]) RangeBUnsafe(f func(i int, v T) bool) {
+	for i, v := range s.value {
+		if !f(i, v) {
+			break
+		}
+	}
+}
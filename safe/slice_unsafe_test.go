@@ -0,0 +1,84 @@
+package safe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSlice_GetUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	if a := age.GetUnsafe(0); a != "foo" {
+		t.Fatalf("Slice.GetUnsafe(0) = %s, wanted %s", a, "foo")
+	}
+}
+
+func TestSlice_SetUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	age.SetUnsafe(0, "bar")
+	if a := age.value[0]; a != "bar" {
+		t.Fatalf("age.value[0] = %s, wanted %s", a, "bar")
+	}
+}
+
+func TestSlice_LenUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	if a := age.LenUnsafe(); a != 1 {
+		t.Fatalf("Slice.LenUnsafe() = %d, wanted %d", a, 1)
+	}
+}
+
+func TestSlice_AppendUnsafe(t *testing.T) {
+	age := &Slice[string]{}
+	age.AppendUnsafe("foo", "bar")
+	exp := []string{"foo", "bar"}
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+}
+
+func TestSlice_InsertUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	age.InsertUnsafe(0, "bar")
+	exp := []string{"bar", "foo"}
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+}
+
+func TestSlice_DeleteUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo", "bar"})
+	age.DeleteUnsafe(1)
+	exp := []string{"foo"}
+	if !reflect.DeepEqual(age.value, exp) {
+		t.Fatalf("age.value = %v, wanted %v", age.value, exp)
+	}
+}
+
+func TestSlice_TruncateUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo", "bar"})
+	age.TruncateUnsafe(0)
+	if a := len(age.value); a != 0 {
+		t.Fatalf("len(age.value) = %d, wanted %d", a, 0)
+	}
+}
+
+func TestSlice_RangeUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo"})
+	age.RangeUnsafe(func(i int, v string) {
+		if v != "foo" {
+			t.Fatalf("v = %s, wanted %s", v, "foo")
+		}
+	})
+}
+
+func TestSlice_RangeBUnsafe(t *testing.T) {
+	age := NewSlice([]string{"foo", "bar"})
+	cnt := 0
+	age.RangeBUnsafe(func(i int, v string) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
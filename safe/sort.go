@@ -0,0 +1,32 @@
+package safe
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// sortValues sorts values in a stable order.
+// Integers, floats and strings are sorted by their values,
+// and other types are sorted by their string representations.
+func sortValues[T any](v []T) {
+	sort.Slice(v, func(i, j int) bool {
+		return lessValue(reflect.ValueOf(v[i]), reflect.ValueOf(v[j]))
+	})
+}
+
+func lessValue(a, b reflect.Value) bool {
+	if a.IsValid() && b.IsValid() && a.Kind() == b.Kind() {
+		switch a.Kind() { //nolint:exhaustive
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.String:
+			return a.String() < b.String()
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
@@ -0,0 +1,504 @@
+package safe
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const sortedMapStringMaxLevel = 32
+
+// SortedMapString wraps map[string]string and keeps keys in ascending order.
+// SortedMapString is backed by a skip list, so Get, Set and Delete cost O(log n)
+// and the range queries by key don't need to copy and sort the whole map.
+// Range, RangeB, RangeFrom, String and MarshalJSON use the ascending order of keys.
+//
+// SortedMapString must be used as the pointer because SortedMapString has sync.RWMutex as a private field.
+// The zero value is an empty map.
+type SortedMapString struct {
+	head   sortedMapStringNode
+	tail   *sortedMapStringNode
+	level  int
+	length int
+	seed   uint64
+	mutex  rwMutex
+}
+
+type sortedMapStringNode struct {
+	key   string
+	value string
+	next  []*sortedMapStringNode
+	prev  *sortedMapStringNode
+}
+
+// NewSortedMapString creates a SortedMapString which has all pairs of the key and value in `value`.
+// Unlike NewMapString, `value` is copied, so it can be used after calling NewSortedMapString.
+func NewSortedMapString(value map[string]string) *SortedMapString {
+	m := &SortedMapString{}
+	for k, v := range value {
+		m.setUnsafe(k, v)
+	}
+	return m
+}
+
+// randomLevel returns the level of a new node.
+// Each level is used with the probability 1/4 of the lower level.
+func (m *SortedMapString) randomLevel() int {
+	if m.seed == 0 {
+		m.seed = 0x9E3779B97F4A7C15
+	}
+	// xorshift64
+	m.seed ^= m.seed << 13
+	m.seed ^= m.seed >> 7
+	m.seed ^= m.seed << 17
+	level := 1
+	for x := m.seed; level < sortedMapStringMaxLevel && x&3 == 0; x >>= 2 {
+		level++
+	}
+	return level
+}
+
+// findUnsafe returns the node of the key and the last nodes whose keys are less than k in each level.
+func (m *SortedMapString) findUnsafe(k string, update *[sortedMapStringMaxLevel]*sortedMapStringNode) *sortedMapStringNode {
+	x := &m.head
+	for i := m.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].key < k {
+			x = x.next[i]
+		}
+		if update != nil {
+			update[i] = x
+		}
+	}
+	if m.level == 0 {
+		return nil
+	}
+	if x = x.next[0]; x != nil && x.key == k {
+		return x
+	}
+	return nil
+}
+
+// ceilingUnsafe returns the first node whose key is greater than or equal to k.
+func (m *SortedMapString) ceilingUnsafe(k string) *sortedMapStringNode {
+	x := &m.head
+	for i := m.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].key < k {
+			x = x.next[i]
+		}
+	}
+	if m.level == 0 {
+		return nil
+	}
+	return x.next[0]
+}
+
+// floorUnsafe returns the last node whose key is less than or equal to k.
+func (m *SortedMapString) floorUnsafe(k string) *sortedMapStringNode {
+	x := &m.head
+	for i := m.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].key <= k {
+			x = x.next[i]
+		}
+	}
+	if x == &m.head {
+		return nil
+	}
+	return x
+}
+
+func (m *SortedMapString) getUnsafe(k string) (string, bool) {
+	if x := m.findUnsafe(k, nil); x != nil {
+		return x.value, true
+	}
+	return "", false
+}
+
+func (m *SortedMapString) setUnsafe(k, v string) {
+	var update [sortedMapStringMaxLevel]*sortedMapStringNode
+	if x := m.findUnsafe(k, &update); x != nil {
+		x.value = v
+		return
+	}
+	if m.head.next == nil {
+		m.head.next = make([]*sortedMapStringNode, sortedMapStringMaxLevel)
+	}
+	level := m.randomLevel()
+	for i := m.level; i < level; i++ {
+		update[i] = &m.head
+	}
+	if level > m.level {
+		m.level = level
+	}
+	x := &sortedMapStringNode{
+		key:   k,
+		value: v,
+		next:  make([]*sortedMapStringNode, level),
+	}
+	for i := 0; i < level; i++ {
+		x.next[i] = update[i].next[i]
+		update[i].next[i] = x
+	}
+	if update[0] != &m.head {
+		x.prev = update[0]
+	}
+	if x.next[0] == nil {
+		m.tail = x
+	} else {
+		x.next[0].prev = x
+	}
+	m.length++
+}
+
+func (m *SortedMapString) deleteUnsafe(k string) (string, bool) {
+	var update [sortedMapStringMaxLevel]*sortedMapStringNode
+	x := m.findUnsafe(k, &update)
+	if x == nil {
+		return "", false
+	}
+	for i := range x.next {
+		update[i].next[i] = x.next[i]
+	}
+	if x.next[0] == nil {
+		m.tail = x.prev
+	} else {
+		x.next[0].prev = x.prev
+	}
+	for m.level > 0 && m.head.next[m.level-1] == nil {
+		m.level--
+	}
+	m.length--
+	return x.value, true
+}
+
+func (m *SortedMapString) firstUnsafe() *sortedMapStringNode {
+	if m.head.next == nil {
+		return nil
+	}
+	return m.head.next[0]
+}
+
+// entries copies all pairs of the key and value in ascending order.
+// The caller must hold the lock.
+func (m *SortedMapString) entries() []mapStringEntry {
+	ret := make([]mapStringEntry, 0, m.length)
+	for x := m.firstUnsafe(); x != nil; x = x.next[0] {
+		ret = append(ret, mapStringEntry{key: x.key, value: x.value})
+	}
+	return ret
+}
+
+func (m *SortedMapString) String() string {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	pairs := make([]string, len(entries))
+	for i, e := range entries {
+		pairs[i] = e.key + ":" + e.value
+	}
+	return "SortedMapString{" + fmt.Sprintf("%v", pairs) + "}"
+}
+
+// MarshalJSON encodes the map as a JSON object whose keys are in ascending order.
+func (m *SortedMapString) MarshalJSON() ([]byte, error) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	return encodeOrderedObject(entries)
+}
+
+// UnmarshalJSON decodes a JSON object and adds the pairs of the key and value.
+// Like MapString, the existing keys which aren't in the document are kept.
+func (m *SortedMapString) UnmarshalJSON(buf []byte) error {
+	var v map[string]string
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	for k, a := range v {
+		m.setUnsafe(k, a)
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+// Get gets a value from the map with lock.
+func (m *SortedMapString) Get(k string) string {
+	m.mutex.RLock()
+	v, _ := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return v
+}
+
+// GetOk gets a value from the map with lock.
+func (m *SortedMapString) GetOk(k string) (string, bool) {
+	m.mutex.RLock()
+	v, ok := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return v, ok
+}
+
+// Has checks whether the map has the key with lock.
+func (m *SortedMapString) Has(k string) bool {
+	m.mutex.RLock()
+	_, ok := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return ok
+}
+
+// Len gets the length of the map with lock.
+func (m *SortedMapString) Len() int {
+	m.mutex.RLock()
+	v := m.length
+	m.mutex.RUnlock()
+	return v
+}
+
+// Delete deletes the key from the map with lock.
+func (m *SortedMapString) Delete(k string) {
+	m.mutex.Lock()
+	m.deleteUnsafe(k)
+	m.mutex.Unlock()
+}
+
+// DeleteR deletes the key from the map and returns the value with lock.
+func (m *SortedMapString) DeleteR(k string) string {
+	m.mutex.Lock()
+	v, _ := m.deleteUnsafe(k)
+	m.mutex.Unlock()
+	return v
+}
+
+// DeleteROk deletes the key from the map and returns the value with lock.
+func (m *SortedMapString) DeleteROk(k string) (string, bool) {
+	m.mutex.Lock()
+	v, ok := m.deleteUnsafe(k)
+	m.mutex.Unlock()
+	return v, ok
+}
+
+// Set sets the key and value to the map with lock.
+func (m *SortedMapString) Set(k, v string) {
+	m.mutex.Lock()
+	m.setUnsafe(k, v)
+	m.mutex.Unlock()
+}
+
+// SetDefault sets the key and value to the map if the map doesn't have the key with lock.
+func (m *SortedMapString) SetDefault(k, v string) {
+	m.SetDefaultR(k, v)
+}
+
+// SetDefaultR sets the key and value to the map if the map doesn't have the key and returns the value with lock.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (m *SortedMapString) SetDefaultR(k, v string) (string, bool) {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	if !ok {
+		m.setUnsafe(k, v)
+		a = v
+	}
+	m.mutex.Unlock()
+	return a, ok
+}
+
+// SetFunc gets a value of the key from the map and calls the function and sets the returned value to the map with lock.
+// This is used to update the value based on the original value atomicaly.
+func (m *SortedMapString) SetFunc(k string, f func(string, bool) string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, ok := m.getUnsafe(k)
+	m.setUnsafe(k, f(v, ok))
+}
+
+// SetFuncE is like SetFunc but the map isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the map isn't updated and the panic is returned as *PanicError.
+func (m *SortedMapString) SetFuncE(k string, f func(string, bool) (string, error)) error {
+	f = recoverMapFunc(f)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, ok := m.getUnsafe(k)
+	a, err := f(v, ok)
+	if err != nil {
+		return err
+	}
+	m.setUnsafe(k, a)
+	return nil
+}
+
+// Swap sets the key and value to the map and returns the previous value with lock.
+// true is returned if the map has had the key.
+func (m *SortedMapString) Swap(k, v string) (string, bool) {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	m.setUnsafe(k, v)
+	m.mutex.Unlock()
+	return a, ok
+}
+
+// CompareAndSwap sets the value of the key only if the map has the key and the current value is equal to old with lock.
+// true is returned if the value is updated.
+func (m *SortedMapString) CompareAndSwap(k, old, v string) bool {
+	m.mutex.Lock()
+	x := m.findUnsafe(k, nil)
+	ok := x != nil && x.value == old
+	if ok {
+		x.value = v
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// CompareAndDelete deletes the key only if the current value is equal to old with lock.
+// true is returned if the key is deleted.
+func (m *SortedMapString) CompareAndDelete(k, old string) bool {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	ok = ok && a == old
+	if ok {
+		m.deleteUnsafe(k)
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// Min gets the smallest key and its value with lock.
+// false is returned if the map is empty.
+func (m *SortedMapString) Min() (string, string, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if x := m.firstUnsafe(); x != nil {
+		return x.key, x.value, true
+	}
+	return "", "", false
+}
+
+// Max gets the largest key and its value with lock.
+// false is returned if the map is empty.
+func (m *SortedMapString) Max() (string, string, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.tail != nil {
+		return m.tail.key, m.tail.value, true
+	}
+	return "", "", false
+}
+
+// Floor gets the largest key which is less than or equal to k and its value with lock.
+// false is returned if there is no such key.
+func (m *SortedMapString) Floor(k string) (string, string, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if x := m.floorUnsafe(k); x != nil {
+		return x.key, x.value, true
+	}
+	return "", "", false
+}
+
+// Ceiling gets the smallest key which is greater than or equal to k and its value with lock.
+// false is returned if there is no such key.
+func (m *SortedMapString) Ceiling(k string) (string, string, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if x := m.ceilingUnsafe(k); x != nil {
+		return x.key, x.value, true
+	}
+	return "", "", false
+}
+
+// Range gets all pairs of the key and value from the map with lock and calls the function in ascending order.
+func (m *SortedMapString) Range(f func(k, v string)) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		f(e.key, e.value)
+	}
+}
+
+// RangeB gets all pairs of the key and value from the map with lock and calls the function in ascending order.
+// If the function returns false, the loop ends.
+func (m *SortedMapString) RangeB(f func(k, v string) bool) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			break
+		}
+	}
+}
+
+// RangeReverse gets all pairs of the key and value from the map with lock and calls the function in descending order.
+func (m *SortedMapString) RangeReverse(f func(k, v string)) {
+	m.RangeBReverse(func(k, v string) bool {
+		f(k, v)
+		return true
+	})
+}
+
+// RangeBReverse gets all pairs of the key and value from the map with lock and calls the function in descending order.
+// If the function returns false, the loop ends.
+func (m *SortedMapString) RangeBReverse(f func(k, v string) bool) {
+	m.mutex.RLock()
+	entries := make([]mapStringEntry, 0, m.length)
+	for x := m.tail; x != nil; x = x.prev {
+		entries = append(entries, mapStringEntry{key: x.key, value: x.value})
+	}
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			break
+		}
+	}
+}
+
+// RangeFrom gets pairs of the key and value whose keys are in the range [lo, hi) with lock
+// and calls the function in ascending order.
+// Only the pairs in the range are copied.
+// If the function returns false, the loop ends.
+func (m *SortedMapString) RangeFrom(lo, hi string, f func(k, v string) bool) {
+	m.mutex.RLock()
+	entries := []mapStringEntry{}
+	for x := m.ceilingUnsafe(lo); x != nil && x.key < hi; x = x.next[0] {
+		entries = append(entries, mapStringEntry{key: x.key, value: x.value})
+	}
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			break
+		}
+	}
+}
+
+// Copy copies all pairs of the key and value to target.
+func (m *SortedMapString) Copy(target *SortedMapString) {
+	m.mutex.RLock()
+	entries := m.entries()
+	m.mutex.RUnlock()
+	target.mutex.Lock()
+	for _, e := range entries {
+		target.setUnsafe(e.key, e.value)
+	}
+	target.mutex.Unlock()
+}
+
+// CopyData copies all pairs of the key and value to target.
+func (m *SortedMapString) CopyData(target map[string]string) {
+	m.mutex.RLock()
+	for x := m.firstUnsafe(); x != nil; x = x.next[0] {
+		target[x.key] = x.value
+	}
+	m.mutex.RUnlock()
+}
+
+// SaveFile encodes the SortedMapString by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (m *SortedMapString) SaveFile(path string) error {
+	return saveFile(path, m)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (m *SortedMapString) LoadFile(path string) error {
+	return loadFile(path, m)
+}
@@ -0,0 +1,362 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func sortedMapStringKeys(m *SortedMapString) []string {
+	keys := []string{}
+	m.Range(func(k, v string) {
+		keys = append(keys, k)
+	})
+	return keys
+}
+
+func TestSortedMapString_random(t *testing.T) {
+	age := &SortedMapString{}
+	ref := map[string]string{}
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	for i := 0; i < 2000; i++ {
+		k := strconv.Itoa(rnd.Intn(300))
+		if rnd.Intn(3) == 0 {
+			age.Delete(k)
+			delete(ref, k)
+			continue
+		}
+		age.Set(k, strconv.Itoa(i))
+		ref[k] = strconv.Itoa(i)
+	}
+	exp := make([]string, 0, len(ref))
+	for k := range ref {
+		exp = append(exp, k)
+	}
+	sort.Strings(exp)
+	if a := sortedMapStringKeys(age); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("keys = %v, wanted %v", a, exp)
+	}
+	if a := age.Len(); a != len(ref) {
+		t.Fatalf("SortedMapString.Len() = %d, wanted %d", a, len(ref))
+	}
+	for k, v := range ref {
+		if a := age.Get(k); a != v {
+			t.Fatalf("SortedMapString.Get(%s) = %s, wanted %s", k, a, v)
+		}
+	}
+	reversed := []string{}
+	age.RangeReverse(func(k, v string) {
+		reversed = append(reversed, k)
+	})
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	if !reflect.DeepEqual(reversed, exp) {
+		t.Fatalf("reversed keys = %v, wanted %v", reversed, exp)
+	}
+}
+
+func TestSortedMapString_String(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"zoo": "world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Set("foo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "SortedMapString{[foo:bar zoo:world]}"
+	if a != exp {
+		t.Fatalf("SortedMapString.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestSortedMapString_MarshalJSON(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"zoo": "world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set("foo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"foo":"bar","zoo":"world"}`
+	if string(b) != exp {
+		t.Fatalf("SortedMapString.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestSortedMapString_UnmarshalJSON(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"foo": "bar"})
+	if err := json.Unmarshal([]byte(`{"zoo":"1","hello":"2"}`), age); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"foo", "hello", "zoo"}
+	if a := sortedMapStringKeys(age); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("keys = %v, wanted %v", a, exp)
+	}
+	if err := json.Unmarshal([]byte(`[]`), age); err == nil {
+		t.Fatal("an array must be rejected")
+	}
+}
+
+func TestSortedMapString_Get(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"foo": "bar"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Get("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", "zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Get("foo"); a != "zoo" {
+		t.Fatalf("SortedMapString.Get() = %s, wanted %s", a, "zoo")
+	}
+	if a, ok := age.GetOk("bar"); a != "" || ok {
+		t.Fatalf("SortedMapString.GetOk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if !age.Has("foo") {
+		t.Fatalf("SortedMapString.Has() = %t, wanted %t", false, true)
+	}
+}
+
+func TestSortedMapString_Delete(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"foo": "bar", "zoo": "world", "hello": "world"})
+	age.Delete("foo")
+	if a := age.DeleteR("zoo"); a != "world" {
+		t.Fatalf("SortedMapString.DeleteR() = %s, wanted %s", a, "world")
+	}
+	if a, ok := age.DeleteROk("hello"); a != "world" || !ok {
+		t.Fatalf("SortedMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "world", true)
+	}
+	if a, ok := age.DeleteROk("hello"); a != "" || ok {
+		t.Fatalf("SortedMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if a := age.Len(); a != 0 {
+		t.Fatalf("SortedMapString.Len() = %d, wanted %d", a, 0)
+	}
+	if _, _, ok := age.Max(); ok {
+		t.Fatal("Max of an empty map must return false")
+	}
+}
+
+func TestSortedMapString_SetDefault(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"foo": "bar"})
+	age.SetDefault("foo", "zoo")
+	if a := age.Get("foo"); a != "bar" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "bar")
+	}
+	if a, ok := age.SetDefaultR("zoo", "world"); a != "world" || ok {
+		t.Fatalf(`age.SetDefaultR("zoo") = %s, %t, wanted %s, %t`, a, ok, "world", false)
+	}
+}
+
+func TestSortedMapString_SetFunc(t *testing.T) {
+	age := &SortedMapString{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			age.SetFunc("foo", func(v string, ok bool) string {
+				return v + "!"
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Get("foo"); a != "!!" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "!!")
+	}
+}
+
+func TestSortedMapString_SetFuncE(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"state": "draft"})
+	errTransition := errors.New("invalid transition")
+	publish := func(v string, ok bool) (string, error) {
+		if v != "draft" {
+			return "", errTransition
+		}
+		return "published", nil
+	}
+	if err := age.SetFuncE("state", publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE("state", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("SortedMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if a := age.Get("state"); a != "published" {
+		t.Fatalf(`SortedMapString.Get("state") = %s, wanted published`, a)
+	}
+	if err := age.SetFuncE("foo", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("SortedMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if age.Has("foo") {
+		t.Fatal(`the key "foo" must not be added`)
+	}
+}
+
+func TestSortedMapString_CompareAndSwap(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"foo": "bar"})
+	if age.CompareAndSwap("foo", "zoo", "world") {
+		t.Fatal("CompareAndSwap must fail if the value isn't equal to old")
+	}
+	if !age.CompareAndSwap("foo", "bar", "world") {
+		t.Fatal("CompareAndSwap must succeed if the value is equal to old")
+	}
+	if a, _ := age.Swap("foo", "hello"); a != "world" {
+		t.Fatalf(`age.Swap("foo") = %s, wanted %s`, a, "world")
+	}
+	if !age.CompareAndDelete("foo", "hello") {
+		t.Fatal("CompareAndDelete must succeed if the value is equal to old")
+	}
+}
+
+func TestSortedMapString_MinMax(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"b": "1", "a": "2", "c": "3"})
+	if k, v, ok := age.Min(); k != "a" || v != "2" || !ok {
+		t.Fatalf("SortedMapString.Min() = %s, %s, %t, wanted %s, %s, %t", k, v, ok, "a", "2", true)
+	}
+	if k, v, ok := age.Max(); k != "c" || v != "3" || !ok {
+		t.Fatalf("SortedMapString.Max() = %s, %s, %t, wanted %s, %s, %t", k, v, ok, "c", "3", true)
+	}
+	if _, _, ok := (&SortedMapString{}).Min(); ok {
+		t.Fatal("Min of an empty map must return false")
+	}
+}
+
+func TestSortedMapString_Floor(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"user:100": "1", "user:200": "2"})
+	data := []struct {
+		key string
+		exp string
+		ok  bool
+	}{
+		{key: "user:050"},
+		{key: "user:100", exp: "user:100", ok: true},
+		{key: "user:150", exp: "user:100", ok: true},
+		{key: "user:300", exp: "user:200", ok: true},
+	}
+	for _, d := range data {
+		if k, _, ok := age.Floor(d.key); k != d.exp || ok != d.ok {
+			t.Fatalf("SortedMapString.Floor(%s) = %s, %t, wanted %s, %t", d.key, k, ok, d.exp, d.ok)
+		}
+	}
+}
+
+func TestSortedMapString_Ceiling(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"user:100": "1", "user:200": "2"})
+	data := []struct {
+		key string
+		exp string
+		ok  bool
+	}{
+		{key: "user:", exp: "user:100", ok: true},
+		{key: "user:100", exp: "user:100", ok: true},
+		{key: "user:150", exp: "user:200", ok: true},
+		{key: "user:300"},
+	}
+	for _, d := range data {
+		if k, _, ok := age.Ceiling(d.key); k != d.exp || ok != d.ok {
+			t.Fatalf("SortedMapString.Ceiling(%s) = %s, %t, wanted %s, %t", d.key, k, ok, d.exp, d.ok)
+		}
+	}
+}
+
+func TestSortedMapString_RangeFrom(t *testing.T) {
+	age := &SortedMapString{}
+	for i := 0; i < 30; i++ {
+		age.Set("user:"+strconv.Itoa(100+i*10), "")
+	}
+	keys := []string{}
+	age.RangeFrom("user:150", "user:200", func(k, v string) bool {
+		keys = append(keys, k)
+		return true
+	})
+	exp := []string{"user:150", "user:160", "user:170", "user:180", "user:190"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+	keys = []string{}
+	age.RangeFrom("user:155", "user:200", func(k, v string) bool {
+		keys = append(keys, k)
+		return len(keys) < 2
+	})
+	exp = []string{"user:160", "user:170"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+}
+
+func TestSortedMapString_RangeB(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"b": "1", "a": "2", "c": "3"})
+	keys := []string{}
+	age.RangeB(func(k, v string) bool {
+		keys = append(keys, k)
+		// RangeB doesn't hold the lock while the function is called.
+		age.Set("d", "4")
+		return len(keys) < 2
+	})
+	exp := []string{"a", "b"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+	keys = []string{}
+	age.RangeBReverse(func(k, v string) bool {
+		keys = append(keys, k)
+		return len(keys) < 2
+	})
+	exp = []string{"d", "c"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+}
+
+func TestSortedMapString_Copy(t *testing.T) {
+	age := NewSortedMapString(map[string]string{"b": "1", "a": "2"})
+	cp := &SortedMapString{}
+	age.Copy(cp)
+	exp := []string{"a", "b"}
+	if a := sortedMapStringKeys(cp); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("keys = %v, wanted %v", a, exp)
+	}
+	data := map[string]string{}
+	age.CopyData(data)
+	if a := len(data); a != 2 {
+		t.Fatalf("len(data) = %d, wanted %d", a, 2)
+	}
+}
+
+func BenchmarkSortedMapString_Set(b *testing.B) {
+	age := &SortedMapString{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		age.Set(strconv.Itoa(i%10000), "bar")
+	}
+}
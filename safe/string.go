@@ -1,8 +1,8 @@
 package safe
 
 import (
+	"context"
 	"encoding/json"
-	"sync"
 )
 
 // String wraps bool.
@@ -10,8 +10,9 @@ import (
 // A RWMutex must not be copied after first use.
 // https://golang.org/pkg/sync/#RWMutex
 type String struct {
-	value string
-	mutex sync.RWMutex
+	value    string
+	mutex    rwMutex
+	watchers watchers[string]
 }
 
 func (s *String) MarshalJSON() ([]byte, error) {
@@ -24,8 +25,49 @@ func (s *String) MarshalJSON() ([]byte, error) {
 func (s *String) UnmarshalJSON(b []byte) error {
 	s.mutex.Lock()
 	err := json.Unmarshal(b, &s.value)
-	s.mutex.Unlock()
-	return err
+	if err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+	s.watchers.notify(s.mutex.Unlock, s.value)
+	return nil
+}
+
+// MarshalText returns the value as it is.
+func (s *String) MarshalText() ([]byte, error) {
+	s.mutex.RLock()
+	v := s.value
+	s.mutex.RUnlock()
+	return []byte(v), nil
+}
+
+// UnmarshalText sets the text as the value.
+func (s *String) UnmarshalText(b []byte) error {
+	v := string(b)
+	s.mutex.Lock()
+	s.value = v
+	s.watchers.notify(s.mutex.Unlock, v)
+	return nil
+}
+
+// MarshalBinary returns the value as it is.
+func (s *String) MarshalBinary() ([]byte, error) {
+	return s.MarshalText()
+}
+
+// UnmarshalBinary sets the data as the value.
+func (s *String) UnmarshalBinary(b []byte) error {
+	return s.UnmarshalText(b)
+}
+
+// GobEncode encodes the value in the same format as MarshalBinary.
+func (s *String) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode decodes the data encoded by GobEncode and sets the value.
+func (s *String) GobDecode(b []byte) error {
+	return s.UnmarshalBinary(b)
 }
 
 func (s *String) String() string {
@@ -45,25 +87,168 @@ func (s *String) Get() string {
 func (s *String) Set(v string) {
 	s.mutex.Lock()
 	s.value = v
-	s.mutex.Unlock()
+	s.watchers.notify(s.mutex.Unlock, v)
 }
 
 func (s *String) SetFunc(f func(v string) string) {
 	s.mutex.Lock()
-	s.value = f(s.value)
-	s.mutex.Unlock()
+	s.value, _ = s.apply(func(v string) (string, error) {
+		return f(v), nil
+	})
+	s.watchers.notify(s.mutex.Unlock, s.value)
+}
+
+// SetFuncE is like SetFunc but the value isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the value isn't updated and the panic is returned as *PanicError.
+func (s *String) SetFuncE(f func(v string) (string, error)) error {
+	f = recoverFunc(f)
+	s.mutex.Lock()
+	v, err := s.apply(f)
+	if err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+	s.value = v
+	s.watchers.notify(s.mutex.Unlock, v)
+	return nil
+}
+
+// apply calls the function with the value and releases the lock if the function panics.
+func (s *String) apply(f func(v string) (string, error)) (string, error) {
+	ok := false
+	defer func() {
+		if !ok {
+			s.mutex.Unlock()
+		}
+	}()
+	v, err := f(s.value)
+	ok = true
+	return v, err
 }
 
 func (s *String) Add(v string) {
 	s.mutex.Lock()
 	s.value += v
-	s.mutex.Unlock()
+	s.watchers.notify(s.mutex.Unlock, s.value)
 }
 
 func (s *String) AddR(v string) string {
 	s.mutex.Lock()
 	a := s.value + v // escapes to heap
 	s.value = a
-	s.mutex.Unlock()
+	s.watchers.notify(s.mutex.Unlock, a)
 	return a
 }
+
+// Swap sets a new value and returns the old value with lock.
+func (s *String) Swap(v string) string {
+	s.mutex.Lock()
+	a := s.value
+	s.value = v
+	s.watchers.notify(s.mutex.Unlock, v)
+	return a
+}
+
+// CompareAndSwap sets a new value only if the current value is equal to old with lock.
+// true is returned if the value is updated.
+func (s *String) CompareAndSwap(old, v string) bool {
+	s.mutex.Lock()
+	if s.value != old {
+		s.mutex.Unlock()
+		return false
+	}
+	s.value = v
+	s.watchers.notify(s.mutex.Unlock, v)
+	return true
+}
+
+// Watch returns a channel which receives the new value after every change.
+// Watch uses WatchCoalesce, so a slow subscriber doesn't stall writers and only the latest value is kept.
+// The channel is closed when the context is done.
+// Changes by the Unsafe methods aren't notified.
+func (s *String) Watch(ctx context.Context) <-chan string {
+	return s.WatchWithPolicy(ctx, WatchCoalesce, 1)
+}
+
+// WatchWithPolicy is like Watch but the policy for a slow subscriber and the buffer size of the channel can be specified.
+func (s *String) WatchWithPolicy(ctx context.Context, policy WatchPolicy, buffer int) <-chan string {
+	return s.watchers.add(ctx, policy, buffer)
+}
+
+func (s *String) mutexes() []*rwMutex {
+	return []*rwMutex{&s.mutex}
+}
+
+// StringReadLocked is a handle of String which is passed to the function of String.RLock.
+// StringReadLocked must not be used after the function returns.
+type StringReadLocked struct {
+	s *String
+}
+
+// StringLocked is a handle of String which is passed to the function of String.Lock.
+// StringLocked must not be used after the function returns.
+type StringLocked struct {
+	StringReadLocked
+	changed bool
+}
+
+// Lock calls the function with a handle with lock.
+// The handle is used to read and write the value together without the Unsafe methods.
+// The function must not call the methods of String.
+// If the handle changes the value, the last value is notified to Watch after the function returns.
+func (s *String) Lock(f func(v *StringLocked)) {
+	v := &StringLocked{StringReadLocked: StringReadLocked{s: s}}
+	s.mutex.Lock()
+	defer func() {
+		v.s = nil
+		if !v.changed {
+			s.mutex.Unlock()
+			return
+		}
+		s.watchers.notify(s.mutex.Unlock, s.value)
+	}()
+	f(v)
+}
+
+// RLock calls the function with a read-only handle with the read lock.
+// The function must not call any method of String.
+// Even a read like Get takes the read lock again, which deadlocks when a writer is waiting for the lock.
+func (s *String) RLock(f func(v *StringReadLocked)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	v := &StringReadLocked{s: s}
+	defer func() {
+		v.s = nil
+	}()
+	f(v)
+}
+
+// Get gets a value.
+func (v *StringReadLocked) Get() string {
+	return v.s.GetUnsafe()
+}
+
+// Set sets a value.
+func (v *StringLocked) Set(a string) {
+	v.s.SetUnsafe(a)
+	v.changed = true
+}
+
+// Add appends a value.
+func (v *StringLocked) Add(a string) {
+	v.s.AddUnsafe(a)
+	v.changed = true
+}
+
+// SaveFile encodes the String by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (s *String) SaveFile(path string) error {
+	return saveFile(path, s)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (s *String) LoadFile(path string) error {
+	return loadFile(path, s)
+}
@@ -1,7 +1,11 @@
 package safe
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
 )
@@ -82,6 +86,41 @@ func TestString_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestString_encoding(t *testing.T) {
+	data := []struct {
+		title   string
+		marshal func(*String) ([]byte, error)
+		decode  func(*String, []byte) error
+	}{
+		{"text", (*String).MarshalText, (*String).UnmarshalText},
+		{"binary", (*String).MarshalBinary, (*String).UnmarshalBinary},
+		{"gob", func(s *String) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(s)
+			return buf.Bytes(), err
+		}, func(s *String, buf []byte) error {
+			return gob.NewDecoder(bytes.NewReader(buf)).Decode(s)
+		}},
+	}
+	for _, d := range data {
+		t.Run(d.title, func(t *testing.T) {
+			for _, exp := range []string{"", "foo", "hello\n\x00world"} {
+				buf, err := d.marshal(&String{value: exp})
+				if err != nil {
+					t.Fatal(err)
+				}
+				a := &String{value: "bar"}
+				if err := d.decode(a, buf); err != nil {
+					t.Fatal(err)
+				}
+				if a.Get() != exp {
+					t.Fatalf("String.Get() = %q, wanted %q", a.Get(), exp)
+				}
+			}
+		})
+	}
+}
+
 func TestString_Get(t *testing.T) {
 	v := "hello"
 	age := &String{value: v}
@@ -149,6 +188,26 @@ func TestString_SetFunc(t *testing.T) {
 	}
 }
 
+func TestString_SetFuncE(t *testing.T) {
+	age := &String{value: "draft"}
+	errTransition := errors.New("invalid transition")
+	publish := func(v string) (string, error) {
+		if v != "draft" {
+			return "", errTransition
+		}
+		return "published", nil
+	}
+	if err := age.SetFuncE(publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE(publish); !errors.Is(err, errTransition) {
+		t.Fatalf("String.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if a := age.Get(); a != "published" {
+		t.Fatalf(`String.Get() = "%s", wanted "published"`, a)
+	}
+}
+
 func TestString_Add(t *testing.T) {
 	age := &String{value: "hello"}
 	var wg sync.WaitGroup
@@ -189,6 +248,108 @@ func TestString_AddR(t *testing.T) {
 	}
 }
 
+func TestString_Swap(t *testing.T) {
+	age := &String{value: "hello"}
+	if a := age.Swap("foo"); a != "hello" {
+		t.Fatalf(`String.Swap() = "%s", wanted "hello"`, a)
+	}
+	if a := age.Get(); a != "foo" {
+		t.Fatalf(`String.Get() = "%s", wanted "foo"`, a)
+	}
+}
+
+func TestString_CompareAndSwap(t *testing.T) {
+	age := &String{value: "hello"}
+	var wg sync.WaitGroup
+	var swapped Int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			if age.CompareAndSwap("hello", "foo") {
+				swapped.Add(1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := swapped.Get(); a != 1 {
+		t.Fatalf("the number of successful CompareAndSwap = %d, wanted 1", a)
+	}
+	if a := age.Get(); a != "foo" {
+		t.Fatalf(`String.Get() = "%s", wanted "foo"`, a)
+	}
+}
+
+func TestString_Watch(t *testing.T) {
+	age := &String{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := age.Watch(ctx)
+	age.Set("foo")
+	if a := receiveWithTimeout(t, ch); a != "foo" {
+		t.Fatalf(`received "%s", wanted "foo"`, a)
+	}
+	age.SetFunc(func(v string) string {
+		return v + "!"
+	})
+	if a := receiveWithTimeout(t, ch); a != "foo!" {
+		t.Fatalf(`received "%s", wanted "foo!"`, a)
+	}
+	if err := json.Unmarshal([]byte(`"bar"`), age); err != nil {
+		t.Fatal(err)
+	}
+	if a := receiveWithTimeout(t, ch); a != "bar" {
+		t.Fatalf(`received "%s", wanted "bar"`, a)
+	}
+	age.CompareAndSwap("foo", "zoo")
+	select {
+	case a := <-ch:
+		t.Fatalf(`received "%s", but failed CompareAndSwap must not be notified`, a)
+	default:
+	}
+}
+
+func TestString_Lock(t *testing.T) {
+	age := &String{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set("a")
+		wg.Done()
+	}()
+	go func() {
+		age.Lock(func(v *StringLocked) {
+			v.Set(v.Get() + "b")
+			v.Add("c")
+		})
+		wg.Done()
+	}()
+	wg.Wait()
+	a := age.Get()
+	if a != "a" && a != "abc" {
+		t.Fatalf(`String.Get() = "%s", wanted "a" or "abc"`, a)
+	}
+	age.RLock(func(v *StringReadLocked) {
+		if b := v.Get(); b != a {
+			t.Fatalf(`StringReadLocked.Get() = "%s", wanted "%s"`, b, a)
+		}
+	})
+}
+
+func TestString_Lock_watch(t *testing.T) {
+	name := &String{value: "foo"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := name.Watch(ctx)
+	name.Lock(func(v *StringLocked) {
+		v.Set("bar")
+		v.Add("baz")
+	})
+	if a := receiveWithTimeout(t, ch); a != "barbaz" {
+		t.Fatalf("received %s, wanted barbaz", a)
+	}
+}
+
 func BenchmarkString_Add(b *testing.B) {
 	age := &String{}
 	b.ResetTimer()
@@ -0,0 +1,440 @@
+package safe
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// TrieMapString wraps map[string]string and indexes keys with a trie.
+// In addition to the MapString API, TrieMapString provides the operations by key prefix,
+// which find the keys under the prefix without scanning the whole map.
+// Range, RangeB, RangePrefix, String and MarshalJSON use the ascending order of keys.
+//
+// TrieMapString must be used as the pointer because TrieMapString has sync.RWMutex as a private field.
+// The zero value is an empty map.
+type TrieMapString struct {
+	root  trieNode
+	mutex rwMutex
+}
+
+type trieNode struct {
+	label    byte
+	value    string
+	ok       bool
+	count    int // the number of keys in the subtree including the node itself
+	children []*trieNode
+}
+
+// NewTrieMapString creates a TrieMapString which has all pairs of the key and value in `value`.
+// Unlike NewMapString, `value` is copied, so it can be used after calling NewTrieMapString.
+func NewTrieMapString(value map[string]string) *TrieMapString {
+	m := &TrieMapString{}
+	for k, v := range value {
+		m.setUnsafe(k, v)
+	}
+	return m
+}
+
+func (n *trieNode) child(c byte) (*trieNode, int) {
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].label >= c
+	})
+	if i < len(n.children) && n.children[i].label == c {
+		return n.children[i], i
+	}
+	return nil, i
+}
+
+// findUnsafe returns the node of the key or nil.
+func (m *TrieMapString) findUnsafe(k string) *trieNode {
+	n := &m.root
+	for i := 0; i < len(k); i++ {
+		if n, _ = n.child(k[i]); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+func (m *TrieMapString) getUnsafe(k string) (string, bool) {
+	if n := m.findUnsafe(k); n != nil && n.ok {
+		return n.value, true
+	}
+	return "", false
+}
+
+func (m *TrieMapString) setUnsafe(k, v string) {
+	if n := m.findUnsafe(k); n != nil && n.ok {
+		n.value = v
+		return
+	}
+	n := &m.root
+	n.count++
+	for i := 0; i < len(k); i++ {
+		c, j := n.child(k[i])
+		if c == nil {
+			c = &trieNode{label: k[i]}
+			n.children = append(n.children, nil)
+			copy(n.children[j+1:], n.children[j:])
+			n.children[j] = c
+		}
+		n = c
+		n.count++
+	}
+	n.value = v
+	n.ok = true
+}
+
+// deleteNodeUnsafe removes the subtree of the prefix or only the node of the key if the prefix is false,
+// and returns the removed node.
+func (m *TrieMapString) deleteNodeUnsafe(k string, prefix bool) *trieNode {
+	target := m.findUnsafe(k)
+	if target == nil {
+		return nil
+	}
+	removed := target.count
+	if !prefix {
+		if !target.ok {
+			return nil
+		}
+		removed = 1
+	}
+	if removed == 0 {
+		return nil
+	}
+	n := &m.root
+	n.count -= removed
+	for i := 0; i < len(k); i++ {
+		c, j := n.child(k[i])
+		c.count -= removed
+		if c.count == 0 {
+			// the rest of the path has no key.
+			n.children = append(n.children[:j], n.children[j+1:]...)
+			return target
+		}
+		n = c
+	}
+	if prefix {
+		n.children = nil
+	}
+	n.ok = false
+	return target
+}
+
+func (m *TrieMapString) deleteUnsafe(k string) (string, bool) {
+	if n := m.deleteNodeUnsafe(k, false); n != nil {
+		return n.value, true
+	}
+	return "", false
+}
+
+// walk calls the function for all keys in the subtree in ascending order.
+// If the function returns false, the walk ends and false is returned.
+func (n *trieNode) walk(key []byte, f func(k []byte, n *trieNode) bool) bool {
+	if n.ok && !f(key, n) {
+		return false
+	}
+	for _, c := range n.children {
+		if !c.walk(append(key, c.label), f) {
+			return false
+		}
+	}
+	return true
+}
+
+// entriesPrefixUnsafe copies all pairs of the key and value under the prefix in ascending order.
+func (m *TrieMapString) entriesPrefixUnsafe(prefix string) []mapStringEntry {
+	n := m.findUnsafe(prefix)
+	if n == nil {
+		return nil
+	}
+	ret := make([]mapStringEntry, 0, n.count)
+	n.walk([]byte(prefix), func(k []byte, n *trieNode) bool {
+		ret = append(ret, mapStringEntry{key: string(k), value: n.value})
+		return true
+	})
+	return ret
+}
+
+func (m *TrieMapString) String() string {
+	m.mutex.RLock()
+	entries := m.entriesPrefixUnsafe("")
+	m.mutex.RUnlock()
+	pairs := make([]string, len(entries))
+	for i, e := range entries {
+		pairs[i] = e.key + ":" + e.value
+	}
+	return "TrieMapString{" + fmt.Sprintf("%v", pairs) + "}"
+}
+
+// MarshalJSON encodes the map as a JSON object whose keys are in ascending order.
+func (m *TrieMapString) MarshalJSON() ([]byte, error) {
+	m.mutex.RLock()
+	entries := m.entriesPrefixUnsafe("")
+	m.mutex.RUnlock()
+	return encodeOrderedObject(entries)
+}
+
+// UnmarshalJSON decodes a JSON object and adds the pairs of the key and value.
+// Like MapString, the existing keys which aren't in the document are kept.
+func (m *TrieMapString) UnmarshalJSON(buf []byte) error {
+	var v map[string]string
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	for k, a := range v {
+		m.setUnsafe(k, a)
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+// Get gets a value from the map with lock.
+func (m *TrieMapString) Get(k string) string {
+	m.mutex.RLock()
+	v, _ := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return v
+}
+
+// GetOk gets a value from the map with lock.
+func (m *TrieMapString) GetOk(k string) (string, bool) {
+	m.mutex.RLock()
+	v, ok := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return v, ok
+}
+
+// Has checks whether the map has the key with lock.
+func (m *TrieMapString) Has(k string) bool {
+	m.mutex.RLock()
+	_, ok := m.getUnsafe(k)
+	m.mutex.RUnlock()
+	return ok
+}
+
+// Len gets the length of the map with lock.
+func (m *TrieMapString) Len() int {
+	m.mutex.RLock()
+	v := m.root.count
+	m.mutex.RUnlock()
+	return v
+}
+
+// Delete deletes the key from the map with lock.
+func (m *TrieMapString) Delete(k string) {
+	m.mutex.Lock()
+	m.deleteUnsafe(k)
+	m.mutex.Unlock()
+}
+
+// DeleteR deletes the key from the map and returns the value with lock.
+func (m *TrieMapString) DeleteR(k string) string {
+	m.mutex.Lock()
+	v, _ := m.deleteUnsafe(k)
+	m.mutex.Unlock()
+	return v
+}
+
+// DeleteROk deletes the key from the map and returns the value with lock.
+func (m *TrieMapString) DeleteROk(k string) (string, bool) {
+	m.mutex.Lock()
+	v, ok := m.deleteUnsafe(k)
+	m.mutex.Unlock()
+	return v, ok
+}
+
+// Set sets the key and value to the map with lock.
+func (m *TrieMapString) Set(k, v string) {
+	m.mutex.Lock()
+	m.setUnsafe(k, v)
+	m.mutex.Unlock()
+}
+
+// SetDefault sets the key and value to the map if the map doesn't have the key with lock.
+func (m *TrieMapString) SetDefault(k, v string) {
+	m.SetDefaultR(k, v)
+}
+
+// SetDefaultR sets the key and value to the map if the map doesn't have the key and returns the value with lock.
+// true is returned if the map has already haven the key and the value isn't updated.
+func (m *TrieMapString) SetDefaultR(k, v string) (string, bool) {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	if !ok {
+		m.setUnsafe(k, v)
+		a = v
+	}
+	m.mutex.Unlock()
+	return a, ok
+}
+
+// SetFunc gets a value of the key from the map and calls the function and sets the returned value to the map with lock.
+// This is used to update the value based on the original value atomicaly.
+func (m *TrieMapString) SetFunc(k string, f func(string, bool) string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, ok := m.getUnsafe(k)
+	m.setUnsafe(k, f(v, ok))
+}
+
+// SetFuncE is like SetFunc but the map isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the map isn't updated and the panic is returned as *PanicError.
+func (m *TrieMapString) SetFuncE(k string, f func(string, bool) (string, error)) error {
+	f = recoverMapFunc(f)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	v, ok := m.getUnsafe(k)
+	a, err := f(v, ok)
+	if err != nil {
+		return err
+	}
+	m.setUnsafe(k, a)
+	return nil
+}
+
+// Swap sets the key and value to the map and returns the previous value with lock.
+// true is returned if the map has had the key.
+func (m *TrieMapString) Swap(k, v string) (string, bool) {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	m.setUnsafe(k, v)
+	m.mutex.Unlock()
+	return a, ok
+}
+
+// CompareAndSwap sets the value of the key only if the map has the key and the current value is equal to old with lock.
+// true is returned if the value is updated.
+func (m *TrieMapString) CompareAndSwap(k, old, v string) bool {
+	m.mutex.Lock()
+	n := m.findUnsafe(k)
+	ok := n != nil && n.ok && n.value == old
+	if ok {
+		n.value = v
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// CompareAndDelete deletes the key only if the current value is equal to old with lock.
+// true is returned if the key is deleted.
+func (m *TrieMapString) CompareAndDelete(k, old string) bool {
+	m.mutex.Lock()
+	a, ok := m.getUnsafe(k)
+	ok = ok && a == old
+	if ok {
+		m.deleteUnsafe(k)
+	}
+	m.mutex.Unlock()
+	return ok
+}
+
+// CountPrefix gets the number of keys which start with the prefix with lock.
+func (m *TrieMapString) CountPrefix(prefix string) int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if n := m.findUnsafe(prefix); n != nil {
+		return n.count
+	}
+	return 0
+}
+
+// DeletePrefix deletes all keys which start with the prefix with lock
+// and returns the number of deleted keys.
+func (m *TrieMapString) DeletePrefix(prefix string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	n := m.findUnsafe(prefix)
+	if n == nil {
+		return 0
+	}
+	cnt := n.count
+	m.deleteNodeUnsafe(prefix, true)
+	return cnt
+}
+
+// LongestPrefixMatch gets the longest key which is a prefix of k and its value with lock.
+// false is returned if there is no such key.
+func (m *TrieMapString) LongestPrefixMatch(k string) (string, string, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	n := &m.root
+	key, value, ok := "", n.value, n.ok
+	for i := 0; i < len(k); i++ {
+		if n, _ = n.child(k[i]); n == nil {
+			break
+		}
+		if n.ok {
+			key, value, ok = k[:i+1], n.value, true
+		}
+	}
+	return key, value, ok
+}
+
+// RangePrefix gets all pairs of the key and value whose keys start with the prefix with lock
+// and calls the function in ascending order.
+// Only the pairs under the prefix are copied.
+// If the function returns false, the loop ends.
+func (m *TrieMapString) RangePrefix(prefix string, f func(k, v string) bool) {
+	m.mutex.RLock()
+	entries := m.entriesPrefixUnsafe(prefix)
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			break
+		}
+	}
+}
+
+// Range gets all pairs of the key and value from the map with lock and calls the function in ascending order.
+func (m *TrieMapString) Range(f func(k, v string)) {
+	m.mutex.RLock()
+	entries := m.entriesPrefixUnsafe("")
+	m.mutex.RUnlock()
+	for _, e := range entries {
+		f(e.key, e.value)
+	}
+}
+
+// RangeB gets all pairs of the key and value from the map with lock and calls the function in ascending order.
+// If the function returns false, the loop ends.
+func (m *TrieMapString) RangeB(f func(k, v string) bool) {
+	m.RangePrefix("", f)
+}
+
+// Copy copies all pairs of the key and value to target.
+func (m *TrieMapString) Copy(target *TrieMapString) {
+	m.mutex.RLock()
+	entries := m.entriesPrefixUnsafe("")
+	m.mutex.RUnlock()
+	target.mutex.Lock()
+	for _, e := range entries {
+		target.setUnsafe(e.key, e.value)
+	}
+	target.mutex.Unlock()
+}
+
+// CopyData copies all pairs of the key and value to target.
+func (m *TrieMapString) CopyData(target map[string]string) {
+	m.mutex.RLock()
+	m.root.walk(nil, func(k []byte, n *trieNode) bool {
+		target[string(k)] = n.value
+		return true
+	})
+	m.mutex.RUnlock()
+}
+
+// SaveFile encodes the TrieMapString by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (m *TrieMapString) SaveFile(path string) error {
+	return saveFile(path, m)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (m *TrieMapString) LoadFile(path string) error {
+	return loadFile(path, m)
+}
@@ -0,0 +1,362 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func trieMapStringKeys(m *TrieMapString) []string {
+	keys := []string{}
+	m.Range(func(k, v string) {
+		keys = append(keys, k)
+	})
+	return keys
+}
+
+func TestTrieMapString_random(t *testing.T) {
+	age := &TrieMapString{}
+	ref := map[string]string{}
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	for i := 0; i < 2000; i++ {
+		k := strconv.FormatInt(int64(rnd.Intn(300)), 3)
+		switch rnd.Intn(6) {
+		case 0:
+			age.Delete(k)
+			delete(ref, k)
+		case 1:
+			cnt := age.DeletePrefix(k)
+			exp := 0
+			for a := range ref {
+				if strings.HasPrefix(a, k) {
+					delete(ref, a)
+					exp++
+				}
+			}
+			if cnt != exp {
+				t.Fatalf("TrieMapString.DeletePrefix(%s) = %d, wanted %d", k, cnt, exp)
+			}
+		default:
+			age.Set(k, strconv.Itoa(i))
+			ref[k] = strconv.Itoa(i)
+		}
+	}
+	exp := make([]string, 0, len(ref))
+	for k := range ref {
+		exp = append(exp, k)
+	}
+	sort.Strings(exp)
+	if a := trieMapStringKeys(age); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("keys = %v, wanted %v", a, exp)
+	}
+	if a := age.Len(); a != len(ref) {
+		t.Fatalf("TrieMapString.Len() = %d, wanted %d", a, len(ref))
+	}
+	for k, v := range ref {
+		if a := age.Get(k); a != v {
+			t.Fatalf("TrieMapString.Get(%s) = %s, wanted %s", k, a, v)
+		}
+	}
+}
+
+func TestTrieMapString_String(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"zoo": "world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Set("foo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "TrieMapString{[foo:bar zoo:world]}"
+	if a != exp {
+		t.Fatalf("TrieMapString.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestTrieMapString_MarshalJSON(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"zoo": "world"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set("foo", "bar")
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"foo":"bar","zoo":"world"}`
+	if string(b) != exp {
+		t.Fatalf("TrieMapString.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestTrieMapString_UnmarshalJSON(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"foo": "bar"})
+	if err := json.Unmarshal([]byte(`{"zoo":"1","hello":"2"}`), age); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"foo", "hello", "zoo"}
+	if a := trieMapStringKeys(age); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("keys = %v, wanted %v", a, exp)
+	}
+	if err := json.Unmarshal([]byte(`[]`), age); err == nil {
+		t.Fatal("an array must be rejected")
+	}
+}
+
+func TestTrieMapString_Get(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"foo": "bar", "": "empty"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Get("foo")
+		wg.Done()
+	}()
+	go func() {
+		age.Set("foo", "zoo")
+		wg.Done()
+	}()
+	wg.Wait()
+	if a := age.Get("foo"); a != "zoo" {
+		t.Fatalf("TrieMapString.Get() = %s, wanted %s", a, "zoo")
+	}
+	if a, ok := age.GetOk("fo"); a != "" || ok {
+		t.Fatalf("TrieMapString.GetOk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if a := age.Get(""); a != "empty" {
+		t.Fatalf("TrieMapString.Get() = %s, wanted %s", a, "empty")
+	}
+	if !age.Has("foo") {
+		t.Fatalf("TrieMapString.Has() = %t, wanted %t", false, true)
+	}
+}
+
+func TestTrieMapString_Delete(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"foo": "bar", "foobar": "world", "fo": "world"})
+	age.Delete("foo")
+	if a := age.DeleteR("foobar"); a != "world" {
+		t.Fatalf("TrieMapString.DeleteR() = %s, wanted %s", a, "world")
+	}
+	if a, ok := age.DeleteROk("fo"); a != "world" || !ok {
+		t.Fatalf("TrieMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "world", true)
+	}
+	if a, ok := age.DeleteROk("fo"); a != "" || ok {
+		t.Fatalf("TrieMapString.DeleteROk() = %s, %t, wanted %s, %t", a, ok, "", false)
+	}
+	if a := age.Len(); a != 0 {
+		t.Fatalf("TrieMapString.Len() = %d, wanted %d", a, 0)
+	}
+	if a := len(age.root.children); a != 0 {
+		t.Fatalf("len(age.root.children) = %d, wanted %d", a, 0)
+	}
+}
+
+func TestTrieMapString_SetDefault(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"foo": "bar"})
+	age.SetDefault("foo", "zoo")
+	if a := age.Get("foo"); a != "bar" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "bar")
+	}
+	if a, ok := age.SetDefaultR("zoo", "world"); a != "world" || ok {
+		t.Fatalf(`age.SetDefaultR("zoo") = %s, %t, wanted %s, %t`, a, ok, "world", false)
+	}
+}
+
+func TestTrieMapString_SetFunc(t *testing.T) {
+	age := &TrieMapString{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			age.SetFunc("foo", func(v string, ok bool) string {
+				return v + "!"
+			})
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if a := age.Get("foo"); a != "!!" {
+		t.Fatalf(`age.Get("foo") = %s, wanted %s`, a, "!!")
+	}
+}
+
+func TestTrieMapString_SetFuncE(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"state": "draft"})
+	errTransition := errors.New("invalid transition")
+	publish := func(v string, ok bool) (string, error) {
+		if v != "draft" {
+			return "", errTransition
+		}
+		return "published", nil
+	}
+	if err := age.SetFuncE("state", publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE("state", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("TrieMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if a := age.Get("state"); a != "published" {
+		t.Fatalf(`TrieMapString.Get("state") = %s, wanted published`, a)
+	}
+	if err := age.SetFuncE("foo", publish); !errors.Is(err, errTransition) {
+		t.Fatalf("TrieMapString.SetFuncE() = %v, wanted %v", err, errTransition)
+	}
+	if age.Has("foo") {
+		t.Fatal(`the key "foo" must not be added`)
+	}
+}
+
+func TestTrieMapString_CompareAndSwap(t *testing.T) {
+	age := NewTrieMapString(map[string]string{"foo": "bar"})
+	if age.CompareAndSwap("fo", "", "world") {
+		t.Fatal("CompareAndSwap must fail if the map doesn't have the key")
+	}
+	if !age.CompareAndSwap("foo", "bar", "world") {
+		t.Fatal("CompareAndSwap must succeed if the value is equal to old")
+	}
+	if a, _ := age.Swap("foo", "hello"); a != "world" {
+		t.Fatalf(`age.Swap("foo") = %s, wanted %s`, a, "world")
+	}
+	if !age.CompareAndDelete("foo", "hello") {
+		t.Fatal("CompareAndDelete must succeed if the value is equal to old")
+	}
+}
+
+func newTestTrieMapString() *TrieMapString {
+	return NewTrieMapString(map[string]string{
+		"tenant/a/config/x": "1",
+		"tenant/a/config/y": "2",
+		"tenant/a/secret":   "3",
+		"tenant/b/config/x": "4",
+		"tenant/":           "5",
+	})
+}
+
+func TestTrieMapString_CountPrefix(t *testing.T) {
+	age := newTestTrieMapString()
+	data := map[string]int{
+		"":                5,
+		"tenant/":         5,
+		"tenant/a/":       3,
+		"tenant/a/config": 2,
+		"tenant/c":        0,
+	}
+	for prefix, exp := range data {
+		if a := age.CountPrefix(prefix); a != exp {
+			t.Fatalf("TrieMapString.CountPrefix(%s) = %d, wanted %d", prefix, a, exp)
+		}
+	}
+}
+
+func TestTrieMapString_DeletePrefix(t *testing.T) {
+	age := newTestTrieMapString()
+	if a := age.DeletePrefix("tenant/a/"); a != 3 {
+		t.Fatalf("TrieMapString.DeletePrefix() = %d, wanted %d", a, 3)
+	}
+	if a := age.DeletePrefix("tenant/c/"); a != 0 {
+		t.Fatalf("TrieMapString.DeletePrefix() = %d, wanted %d", a, 0)
+	}
+	exp := []string{"tenant/", "tenant/b/config/x"}
+	if a := trieMapStringKeys(age); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("keys = %v, wanted %v", a, exp)
+	}
+	if a := age.DeletePrefix(""); a != 2 {
+		t.Fatalf("TrieMapString.DeletePrefix() = %d, wanted %d", a, 2)
+	}
+	if a := age.Len(); a != 0 {
+		t.Fatalf("TrieMapString.Len() = %d, wanted %d", a, 0)
+	}
+}
+
+func TestTrieMapString_LongestPrefixMatch(t *testing.T) {
+	age := newTestTrieMapString()
+	data := []struct {
+		key string
+		exp string
+		ok  bool
+	}{
+		{key: "tenant/a/config/x/z", exp: "tenant/a/config/x", ok: true},
+		{key: "tenant/a/config", exp: "tenant/", ok: true},
+		{key: "tenant/b/config/x", exp: "tenant/b/config/x", ok: true},
+		{key: "tenant"},
+		{key: "user/"},
+	}
+	for _, d := range data {
+		if k, _, ok := age.LongestPrefixMatch(d.key); k != d.exp || ok != d.ok {
+			t.Fatalf("TrieMapString.LongestPrefixMatch(%s) = %s, %t, wanted %s, %t", d.key, k, ok, d.exp, d.ok)
+		}
+	}
+}
+
+func TestTrieMapString_RangePrefix(t *testing.T) {
+	age := newTestTrieMapString()
+	keys := []string{}
+	age.RangePrefix("tenant/a/", func(k, v string) bool {
+		keys = append(keys, k)
+		// RangePrefix doesn't hold the lock while the function is called.
+		age.Set("tenant/a/z", "6")
+		return true
+	})
+	exp := []string{"tenant/a/config/x", "tenant/a/config/y", "tenant/a/secret"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+	keys = []string{}
+	age.RangePrefix("tenant/", func(k, v string) bool {
+		keys = append(keys, k)
+		return len(keys) < 2
+	})
+	exp = []string{"tenant/", "tenant/a/config/x"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys = %v, wanted %v", keys, exp)
+	}
+}
+
+func TestTrieMapString_RangeB(t *testing.T) {
+	age := newTestTrieMapString()
+	cnt := 0
+	age.RangeB(func(k, v string) bool {
+		cnt++
+		return false
+	})
+	if cnt != 1 {
+		t.Fatalf("cnt = %d, wanted %d", cnt, 1)
+	}
+}
+
+func TestTrieMapString_Copy(t *testing.T) {
+	age := newTestTrieMapString()
+	cp := &TrieMapString{}
+	age.Copy(cp)
+	if a := cp.Len(); a != 5 {
+		t.Fatalf("cp.Len() = %d, wanted %d", a, 5)
+	}
+	data := map[string]string{}
+	age.CopyData(data)
+	if a := data["tenant/a/secret"]; a != "3" {
+		t.Fatalf(`data["tenant/a/secret"] = %s, wanted %s`, a, "3")
+	}
+}
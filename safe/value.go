@@ -0,0 +1,115 @@
+package safe
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Value wraps a value of any type.
+// Value must be used as the pointer because Value has sync.RWMutex as a private field.
+// A RWMutex must not be copied after first use.
+// https://golang.org/pkg/sync/#RWMutex
+//
+// Note that Value only guards the value itself.
+// If T is a pointer, slice or map, the data it refers to isn't protected.
+type Value[T any] struct {
+	value T
+	mutex rwMutex
+}
+
+func (val *Value[T]) String() string {
+	val.mutex.RLock()
+	v := "Value{" + fmt.Sprintf("%v", val.value) + "}"
+	val.mutex.RUnlock()
+	return v
+}
+
+func (val *Value[T]) MarshalJSON() ([]byte, error) {
+	val.mutex.RLock()
+	b, err := json.Marshal(val.value)
+	val.mutex.RUnlock()
+	return b, err
+}
+
+func (val *Value[T]) UnmarshalJSON(b []byte) error {
+	val.mutex.Lock()
+	err := json.Unmarshal(b, &val.value)
+	val.mutex.Unlock()
+	return err
+}
+
+// Get gets a value with lock.
+func (val *Value[T]) Get() T {
+	val.mutex.RLock()
+	v := val.value
+	val.mutex.RUnlock()
+	return v
+}
+
+// Set sets a value with lock.
+func (val *Value[T]) Set(v T) {
+	val.mutex.Lock()
+	val.value = v
+	val.mutex.Unlock()
+}
+
+// SetFunc gets a value and calls the function and sets the returned value with lock.
+// This is used to update the value based on the original value atomicaly.
+func (val *Value[T]) SetFunc(f func(v T) T) {
+	val.mutex.Lock()
+	defer val.mutex.Unlock()
+	val.value = f(val.value)
+}
+
+// SetFuncE is like SetFunc but the value isn't updated if the function returns an error,
+// and the error is returned.
+// If the function panics, the value isn't updated and the panic is returned as *PanicError.
+func (val *Value[T]) SetFuncE(f func(v T) (T, error)) error {
+	f = recoverFunc(f)
+	val.mutex.Lock()
+	defer val.mutex.Unlock()
+	v, err := f(val.value)
+	if err != nil {
+		return err
+	}
+	val.value = v
+	return nil
+}
+
+// Swap sets a new value and returns the old value with lock.
+func (val *Value[T]) Swap(v T) T {
+	val.mutex.Lock()
+	a := val.value
+	val.value = v
+	val.mutex.Unlock()
+	return a
+}
+
+// CompareAndSwap sets a new value only if the current value is equal to old with lock.
+// true is returned if the value is updated.
+// Like sync.Map, CompareAndSwap panics if T isn't comparable.
+func (val *Value[T]) CompareAndSwap(old, v T) bool {
+	val.mutex.Lock()
+	defer val.mutex.Unlock()
+	if any(val.value) != any(old) {
+		return false
+	}
+	val.value = v
+	return true
+}
+
+func (val *Value[T]) mutexes() []*rwMutex {
+	return []*rwMutex{&val.mutex}
+}
+
+// SaveFile encodes the Value by MarshalJSON and writes it to the file atomically.
+// The data is written to a temporary file and the file is renamed to path.
+func (val *Value[T]) SaveFile(path string) error {
+	return saveFile(path, val)
+}
+
+// LoadFile reads the file written by SaveFile and decodes it by UnmarshalJSON.
+// ErrCorruptFile is returned if the file is broken.
+func (val *Value[T]) LoadFile(path string) error {
+	return loadFile(path, val)
+}
@@ -0,0 +1,219 @@
+package safe
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func TestValue_String(t *testing.T) {
+	age := &Value[testPoint]{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := ""
+	go func() {
+		age.Set(testPoint{X: 1, Y: 2})
+		wg.Done()
+	}()
+	go func() {
+		a = age.String()
+		wg.Done()
+	}()
+	wg.Wait()
+	a = age.String()
+	exp := "Value{{1 2}}"
+	if a != exp {
+		t.Fatalf("Value.String() = %s, wanted %s", a, exp)
+	}
+}
+
+func TestValue_MarshalJSON(t *testing.T) {
+	age := &Value[testPoint]{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set(testPoint{X: 1, Y: 2})
+		wg.Done()
+	}()
+	go func() {
+		_, err = json.Marshal(age)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(age)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"x":1,"y":2}`
+
+	if string(b) != exp {
+		t.Fatalf("Value.MarshalJSON() = %s, wanted %s", string(b), exp)
+	}
+}
+
+func TestValue_UnmarshalJSON(t *testing.T) {
+	age := &Value[time.Duration]{}
+	var wg sync.WaitGroup
+	buf := []byte("10")
+	wg.Add(2)
+	var err error
+	go func() {
+		age.Set(5)
+		wg.Done()
+	}()
+	go func() {
+		err = json.Unmarshal(buf, age)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf, age); err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Duration(10)
+
+	if age.value != exp {
+		t.Fatalf("Value.UnmarshalJSON() = %d, wanted %d", age.value, exp)
+	}
+}
+
+func TestValue_Get(t *testing.T) {
+	v := testPoint{X: 1, Y: 2}
+	age := &Value[testPoint]{value: v}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	a := testPoint{}
+	b := testPoint{}
+	go func() {
+		a = age.Get()
+		wg.Done()
+	}()
+	go func() {
+		b = age.Get()
+		wg.Done()
+	}()
+	wg.Wait()
+	if a != v {
+		t.Fatalf("Value.Get() = %v, wanted %v", a, v)
+	}
+	if b != v {
+		t.Fatalf("Value.Get() = %v, wanted %v", b, v)
+	}
+}
+
+func TestValue_Set(t *testing.T) {
+	age := &Value[testPoint]{}
+	exp := testPoint{X: 1, Y: 2}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.Set(exp)
+		wg.Done()
+	}()
+	go func() {
+		age.Set(exp)
+		wg.Done()
+	}()
+	wg.Wait()
+	a := age.Get()
+	if a != exp {
+		t.Fatalf("Value.Get() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestValue_SetFunc(t *testing.T) {
+	age := &Value[testPoint]{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		age.SetFunc(func(v testPoint) testPoint {
+			v.X++
+			return v
+		})
+		wg.Done()
+	}()
+	go func() {
+		age.SetFunc(func(v testPoint) testPoint {
+			v.X += 2
+			return v
+		})
+		wg.Done()
+	}()
+	wg.Wait()
+	a := age.Get()
+	exp := testPoint{X: 3}
+	if a != exp {
+		t.Fatalf("Value.Get() = %v, wanted %v", a, exp)
+	}
+}
+
+func TestValue_SetFuncE(t *testing.T) {
+	age := &Value[testPoint]{}
+	errOutOfRange := errors.New("out of range")
+	move := func(v testPoint) (testPoint, error) {
+		if v.X >= 1 {
+			return v, errOutOfRange
+		}
+		v.X++
+		return v, nil
+	}
+	if err := age.SetFuncE(move); err != nil {
+		t.Fatal(err)
+	}
+	if err := age.SetFuncE(move); !errors.Is(err, errOutOfRange) {
+		t.Fatalf("Value.SetFuncE() = %v, wanted %v", err, errOutOfRange)
+	}
+	if a := age.Get(); a.X != 1 {
+		t.Fatalf("Value.Get().X = %d, wanted %d", a.X, 1)
+	}
+}
+
+func TestValue_Swap(t *testing.T) {
+	age := &Value[testPoint]{value: testPoint{X: 1}}
+	if a := age.Swap(testPoint{X: 2}); a.X != 1 {
+		t.Fatalf("Value.Swap() = %v, wanted %v", a, testPoint{X: 1})
+	}
+	if a := age.Get(); a.X != 2 {
+		t.Fatalf("Value.Get() = %v, wanted %v", a, testPoint{X: 2})
+	}
+}
+
+func TestValue_CompareAndSwap(t *testing.T) {
+	age := &Value[testPoint]{}
+	if age.CompareAndSwap(testPoint{X: 1}, testPoint{X: 2}) {
+		t.Fatal("CompareAndSwap must fail if the value isn't equal to old")
+	}
+	if !age.CompareAndSwap(testPoint{}, testPoint{X: 2}) {
+		t.Fatal("CompareAndSwap must succeed if the value is equal to old")
+	}
+	if a := age.Get(); a.X != 2 {
+		t.Fatalf("Value.Get() = %v, wanted %v", a, testPoint{X: 2})
+	}
+}
+
+func TestValue_CompareAndSwap_notComparable(t *testing.T) {
+	age := &Value[[]int]{}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("CompareAndSwap must panic if the type isn't comparable")
+		}
+		// the lock must be released.
+		age.Set([]int{1})
+	}()
+	age.CompareAndSwap([]int{}, []int{1})
+}
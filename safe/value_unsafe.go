@@ -0,0 +1,11 @@
+package safe
+
+// GetUnsafe gets a value without lock.
+func (val *Value[T]) GetUnsafe() T {
+	return val.value
+}
+
+// SetUnsafe sets a value without lock.
+func (val *Value[T]) SetUnsafe(v T) {
+	val.value = v
+}
@@ -0,0 +1,23 @@
+package safe
+
+import (
+	"testing"
+)
+
+func TestValue_GetUnsafe(t *testing.T) {
+	v := 3.5
+	age := &Value[float64]{value: v}
+	a := age.GetUnsafe()
+	if a != v {
+		t.Fatalf("Value.GetUnsafe() = %f, wanted %f", a, v)
+	}
+}
+
+func TestValue_SetUnsafe(t *testing.T) {
+	v := 3.5
+	age := &Value[float64]{}
+	age.SetUnsafe(v)
+	if age.value != v {
+		t.Fatalf("Value.GetUnsafe() = %f, wanted %f", age.value, v)
+	}
+}
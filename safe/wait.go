@@ -0,0 +1,130 @@
+package safe
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type waiter[T any] struct {
+	// get returns the current value, which is checked first and by recheck.
+	get func() T
+	// filter is called by writers to skip the values which the waiter doesn't need.
+	// filter must be an internal function which returns quickly and doesn't panic.
+	filter func(T) bool
+	// pending has the values which are written after the waiter checked them last time.
+	pending []T
+	signal  chan struct{}
+}
+
+// waiters is a list of goroutines waiting for a condition.
+// Writers call wake with the value they have written, which only queues the value to the waiters,
+// and each waiter checks the value in its own goroutine.
+// So a waiter doesn't miss the value even if it is overwritten immediately,
+// and a slow or panicking condition doesn't affect writers.
+type waiters[T any] struct {
+	n     atomic.Int32
+	list  []*waiter[T]
+	mutex sync.Mutex
+}
+
+// wait blocks until the value returned by get or a value passed to wake satisfies cond,
+// or the context is done.
+// cond is called in the goroutine of the caller.
+func (ws *waiters[T]) wait(ctx context.Context, get func() T, cond func(T) bool) (T, error) {
+	return ws.block(ctx, ws.add(get, nil), cond)
+}
+
+// add registers a waiter and queues the value returned by get as the first value to check.
+// The waiter is counted before get is called,
+// so a writer which changes the value after get is called always wakes the waiter.
+// The values which don't satisfy filter aren't queued. filter may be nil.
+// The waiter must be passed to block.
+func (ws *waiters[T]) add(get func() T, filter func(T) bool) *waiter[T] {
+	w := &waiter[T]{get: get, filter: filter, signal: make(chan struct{}, 1)}
+	ws.mutex.Lock()
+	ws.n.Add(1)
+	ws.queueUnsafe(w, get())
+	ws.list = append(ws.list, w)
+	ws.mutex.Unlock()
+	return w
+}
+
+// recheck queues the current values to all waiters and signals them.
+// This is used after the container is changed without wake, for example by the Unsafe methods in Atomically.
+// The caller must hold the lock which get requires.
+func (ws *waiters[T]) recheck() {
+	if ws.n.Load() == 0 {
+		return
+	}
+	ws.mutex.Lock()
+	for _, w := range ws.list {
+		ws.queueUnsafe(w, w.get())
+	}
+	ws.mutex.Unlock()
+}
+
+// block waits until a value queued to the waiter satisfies cond or the context is done, and removes the waiter.
+// If cond is nil, the first queued value is returned.
+// The waiter is removed even if cond panics.
+func (ws *waiters[T]) block(ctx context.Context, w *waiter[T], cond func(T) bool) (T, error) {
+	defer ws.remove(w)
+	for {
+		ws.mutex.Lock()
+		values := w.pending
+		w.pending = nil
+		ws.mutex.Unlock()
+		for _, v := range values {
+			if cond == nil || cond(v) {
+				return v, nil
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			var v T
+			return v, err
+		}
+		select {
+		case <-w.signal:
+		case <-ctx.Done():
+			// check the values written before the context was done.
+		}
+	}
+}
+
+// wake queues the value to all waiters and signals them.
+func (ws *waiters[T]) wake(v T) {
+	if ws.n.Load() == 0 {
+		return
+	}
+	ws.mutex.Lock()
+	for _, w := range ws.list {
+		ws.queueUnsafe(w, v)
+	}
+	ws.mutex.Unlock()
+}
+
+// queueUnsafe queues the value to the waiter if the value satisfies the filter, and signals the waiter.
+// The caller must hold the mutex of waiters.
+func (ws *waiters[T]) queueUnsafe(w *waiter[T], v T) {
+	if w.filter != nil && !w.filter(v) {
+		return
+	}
+	w.pending = append(w.pending, v)
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// remove removes the waiter.
+func (ws *waiters[T]) remove(w *waiter[T]) {
+	ws.mutex.Lock()
+	for i, a := range ws.list {
+		if a == w {
+			ws.list = append(ws.list[:i], ws.list[i+1:]...)
+			ws.n.Add(-1)
+			break
+		}
+	}
+	ws.mutex.Unlock()
+}
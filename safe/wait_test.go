@@ -0,0 +1,97 @@
+package safe
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaiters_wake(t *testing.T) {
+	ws := &waiters[int]{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan int)
+	for _, exp := range []int{1, 2} {
+		exp := exp
+		go func() {
+			a, err := ws.wait(ctx, func() int { return 0 }, func(v int) bool { return v == exp })
+			if err != nil {
+				t.Error(err)
+			}
+			done <- a
+		}()
+	}
+	for ws.n.Load() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	ws.wake(2)
+	if a := <-done; a != 2 {
+		t.Fatalf("woken with %d, wanted %d", a, 2)
+	}
+	if a := ws.n.Load(); a != 1 {
+		t.Fatalf("the number of waiters = %d, wanted %d", a, 1)
+	}
+	ws.wake(1)
+	if a := <-done; a != 1 {
+		t.Fatalf("woken with %d, wanted %d", a, 1)
+	}
+}
+
+func TestWaiters_panic(t *testing.T) {
+	ws := &waiters[int]{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan any)
+	go func() {
+		defer func() {
+			done <- recover()
+		}()
+		_, _ = ws.wait(ctx, func() int { return 0 }, func(v int) bool {
+			if v == 1 {
+				panic("cond")
+			}
+			return false
+		})
+	}()
+	for ws.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the condition is called in the goroutine of the waiter, so the writer doesn't panic.
+	ws.wake(1)
+	if a := <-done; a != "cond" {
+		t.Fatalf("the waiter recovered %v, wanted %v", a, "cond")
+	}
+	if a := ws.n.Load(); a != 0 {
+		t.Fatalf("the number of waiters = %d, wanted %d", a, 0)
+	}
+	ws.wake(2)
+}
+
+func TestWaiters_slow(t *testing.T) {
+	ws := &waiters[int]{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	release := make(chan struct{})
+	done := make(chan int)
+	go func() {
+		a, err := ws.wait(ctx, func() int { return 0 }, func(v int) bool {
+			<-release
+			return v == 2
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- a
+	}()
+	for ws.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the writer isn't blocked by the slow condition, and no value is missed.
+	ws.wake(1)
+	ws.wake(2)
+	ws.wake(3)
+	close(release)
+	if a := <-done; a != 2 {
+		t.Fatalf("woken with %d, wanted %d", a, 2)
+	}
+}
@@ -0,0 +1,159 @@
+package safe
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// WatchPolicy decides what happens when a subscriber doesn't receive values as fast as they are sent.
+type WatchPolicy int
+
+const (
+	// WatchCoalesce discards the oldest pending value when the buffer of the subscriber is full,
+	// so the latest value is always delivered and writers are never blocked.
+	// With the buffer size 1, the subscriber always gets the latest value eventually.
+	WatchCoalesce WatchPolicy = iota
+	// WatchDrop discards the new value when the buffer of the subscriber is full.
+	// Writers are never blocked.
+	WatchDrop
+	// WatchBlock blocks writers until the subscriber receives the value or the context of the subscriber is done.
+	// Values are sent after the lock of the container is released, so the subscriber can read the container
+	// between receives. The subscriber must not change the container while it is subscribing,
+	// because the change waits for the value which the subscriber hasn't received yet.
+	WatchBlock
+)
+
+// defaultMapWatchBuffer is the buffer size of the channel returned by Map.Watch.
+const defaultMapWatchBuffer = 64
+
+type watcher[T any] struct {
+	ch     chan T
+	done   <-chan struct{}
+	policy WatchPolicy
+}
+
+// watchers is a list of subscribers.
+// Values are sent with the mutex of watchers,
+// so all subscribers receive values in the same order.
+// Values of the containers with a mutex are queued with the lock of the container
+// and sent after the lock is released, so a blocked subscriber doesn't block the container.
+type watchers[T any] struct {
+	n          atomic.Int32
+	list       []*watcher[T]
+	mutex      sync.Mutex
+	queue      []T
+	queueMutex sync.Mutex
+}
+
+// add registers a subscriber and returns its channel.
+// The channel is closed when the context is done.
+func (ws *watchers[T]) add(ctx context.Context, policy WatchPolicy, buffer int) <-chan T {
+	if buffer < 1 {
+		buffer = 1
+	}
+	w := &watcher[T]{
+		ch:     make(chan T, buffer),
+		done:   ctx.Done(),
+		policy: policy,
+	}
+	ws.mutex.Lock()
+	ws.list = append(ws.list, w)
+	ws.n.Add(1)
+	ws.mutex.Unlock()
+	go func() {
+		<-ctx.Done()
+		ws.remove(w)
+	}()
+	return w.ch
+}
+
+func (ws *watchers[T]) remove(w *watcher[T]) {
+	ws.mutex.Lock()
+	for i, a := range ws.list {
+		if a == w {
+			ws.list = append(ws.list[:i], ws.list[i+1:]...)
+			ws.n.Add(-1)
+			break
+		}
+	}
+	close(w.ch)
+	ws.mutex.Unlock()
+}
+
+// notify queues values, releases the lock of the container by calling unlock and sends the queued values to all subscribers.
+// Values are queued before unlock is called, so they are sent in the same order as the changes.
+// notify returns after the values are sent, even if another goroutine sends them.
+func (ws *watchers[T]) notify(unlock func(), values ...T) {
+	if ws.n.Load() == 0 || len(values) == 0 {
+		unlock()
+		return
+	}
+	ws.queueMutex.Lock()
+	ws.queue = append(ws.queue, values...)
+	ws.queueMutex.Unlock()
+	unlock()
+	ws.mutex.Lock()
+	for {
+		ws.queueMutex.Lock()
+		queue := ws.queue
+		ws.queue = nil
+		ws.queueMutex.Unlock()
+		if len(queue) == 0 {
+			break
+		}
+		for _, v := range queue {
+			ws.send(v)
+		}
+	}
+	ws.mutex.Unlock()
+}
+
+// notifyFunc sends the value returned by get to all subscribers.
+// This is used by the lock-free containers.
+// get is called with the mutex of watchers,
+// so the last notification always sends the latest value.
+func (ws *watchers[T]) notifyFunc(get func() T) {
+	if ws.n.Load() == 0 {
+		return
+	}
+	ws.mutex.Lock()
+	ws.send(get())
+	ws.mutex.Unlock()
+}
+
+// send sends a value to all subscribers.
+// The caller must hold the mutex of watchers.
+func (ws *watchers[T]) send(v T) {
+	for _, w := range ws.list {
+		switch w.policy {
+		case WatchBlock:
+			select {
+			case w.ch <- v:
+			case <-w.done:
+			}
+		case WatchDrop:
+			select {
+			case w.ch <- v:
+			default:
+			}
+		default:
+			sendCoalesce(w.ch, v)
+		}
+	}
+}
+
+// sendCoalesce sends a value to the channel, discarding the oldest values until the channel has room.
+func sendCoalesce[T any](ch chan T, v T) {
+	for {
+		select {
+		case ch <- v:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package safe
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func receiveWithTimeout[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatal("the channel is closed")
+		}
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	var v T
+	return v
+}
+
+func TestWatchPolicy_coalesce(t *testing.T) {
+	ws := &watchers[int]{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := ws.add(ctx, WatchCoalesce, 2)
+	ws.notifyFunc(func() int { return 1 })
+	ws.notifyFunc(func() int { return 2 })
+	ws.notifyFunc(func() int { return 3 })
+	if a := receiveWithTimeout(t, ch); a != 2 {
+		t.Fatalf("received %d, wanted %d", a, 2)
+	}
+	if a := receiveWithTimeout(t, ch); a != 3 {
+		t.Fatalf("received %d, wanted %d", a, 3)
+	}
+}
+
+func TestWatchPolicy_drop(t *testing.T) {
+	ws := &watchers[int]{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := ws.add(ctx, WatchDrop, 2)
+	ws.notifyFunc(func() int { return 1 })
+	ws.notifyFunc(func() int { return 2 })
+	ws.notifyFunc(func() int { return 3 })
+	if a := receiveWithTimeout(t, ch); a != 1 {
+		t.Fatalf("received %d, wanted %d", a, 1)
+	}
+	if a := receiveWithTimeout(t, ch); a != 2 {
+		t.Fatalf("received %d, wanted %d", a, 2)
+	}
+	select {
+	case a := <-ch:
+		t.Fatalf("received %d, but the value must be dropped", a)
+	default:
+	}
+}
+
+func TestWatchPolicy_block(t *testing.T) {
+	ws := &watchers[int]{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := ws.add(ctx, WatchBlock, 1)
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i <= 3; i++ {
+			i := i
+			ws.notifyFunc(func() int { return i })
+		}
+		close(done)
+	}()
+	for i := 1; i <= 3; i++ {
+		if a := receiveWithTimeout(t, ch); a != i {
+			t.Fatalf("received %d, wanted %d", a, i)
+		}
+	}
+	<-done
+}
+
+func TestWatchPolicy_blockCanceled(t *testing.T) {
+	ws := &watchers[int]{}
+	ctx, cancel := context.WithCancel(context.Background())
+	ws.add(ctx, WatchBlock, 1)
+	ws.notifyFunc(func() int { return 1 })
+	done := make(chan struct{})
+	go func() {
+		// this blocks until the context is canceled.
+		ws.notifyFunc(func() int { return 2 })
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the writer must not be blocked after the context is canceled")
+	}
+}
+
+func TestWatchPolicy_blockRead(t *testing.T) {
+	m := NewMapString(map[string]string{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := m.WatchWithPolicy(ctx, WatchBlock, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			for j := 0; j < 200; j++ {
+				m.Set(strconv.Itoa(i), strconv.Itoa(j))
+			}
+			wg.Done()
+		}()
+	}
+	go func() {
+		for e := range ch {
+			// let the writers fill the buffer and wait for the subscriber.
+			time.Sleep(100 * time.Microsecond)
+			// the subscriber can read the map while writers are blocked.
+			m.Get(e.Key)
+		}
+	}()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the writers and the subscriber which reads the map must not deadlock")
+	}
+}
+
+func TestWatchers_close(t *testing.T) {
+	ws := &watchers[int]{}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ws.add(ctx, WatchCoalesce, 1)
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("no value must be sent")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the channel must be closed after the context is canceled")
+	}
+	if a := ws.n.Load(); a != 0 {
+		t.Fatalf("the number of subscribers = %d, wanted %d", a, 0)
+	}
+	// notify after the subscriber is removed must not panic.
+	ws.notifyFunc(func() int { return 1 })
+}